@@ -0,0 +1,154 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"mdb/internal/data"
+	"mdb/internal/validation"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/gin-gonic/gin/binding"
+)
+
+const (
+	batchAtomic     = "atomic"
+	batchBestEffort = "best_effort"
+)
+
+var errInvalidOp = errors.New("operation failed validation")
+
+type batchOpInput struct {
+	Op      string          `json:"op" binding:"required,oneof=create update delete"`
+	ID      int64           `json:"id" binding:"omitempty,min=1"`
+	Version int32           `json:"version" binding:"omitempty,min=1"`
+	Movie   json.RawMessage `json:"movie"`
+}
+
+type batchOpResult struct {
+	Index  int               `json:"index"`
+	Op     string            `json:"op"`
+	Status string            `json:"status"`
+	Movie  *data.Movie       `json:"movie,omitempty"`
+	Errors map[string]string `json:"errors,omitempty"`
+	Error  string            `json:"error,omitempty"`
+}
+
+// batchOp validates one operation the same way the single movie handlers do and
+// turns it into a data.BatchOp. The returned map holds the validation errors.
+func (a *application) batchOp(in batchOpInput) (*data.BatchOp, map[string]string) {
+	op := &data.BatchOp{Op: in.Op, ID: in.ID, Version: in.Version}
+	if err := binding.Validator.ValidateStruct(in); err != nil {
+		return op, validation.Errors(err)
+	}
+	if in.Op != data.BatchCreate && in.ID == 0 {
+		return op, map[string]string{"ID": "Is needed"}
+	}
+	switch in.Op {
+	case data.BatchCreate:
+		var movie data.Movie
+		if err := decodeStrict(bytes.NewReader(in.Movie), &movie); err != nil {
+			return op, validation.Errors(err)
+		}
+		if err := binding.Validator.ValidateStruct(&movie); err != nil {
+			return op, validation.Errors(err)
+		}
+		movie.Genres = a.genres.Normalize(movie.Genres)
+		op.Movie = &movie
+	case data.BatchUpdate:
+		if in.Version == 0 {
+			return op, map[string]string{"Version": "Is needed"}
+		}
+		var input movieUpdateInput
+		if err := decodeStrict(bytes.NewReader(in.Movie), &input); err != nil {
+			return op, validation.Errors(err)
+		}
+		if err := binding.Validator.ValidateStruct(&input); err != nil {
+			return op, validation.Errors(err)
+		}
+		if input.Genres != nil {
+			input.Genres = a.genres.Normalize(input.Genres)
+		}
+		op.Update = &data.MovieUpdate{
+			Title:   input.Title,
+			Year:    input.Year,
+			Runtime: input.Runtime,
+			Genres:  input.Genres,
+		}
+	}
+	return op, nil
+}
+
+type batchMoviesInput struct {
+	Mode       string         `json:"mode" binding:"omitempty,oneof=atomic best_effort"`
+	Operations []batchOpInput `json:"operations" binding:"required,min=1,max=100"`
+}
+
+// batchMoviesHandler runs many create/update/delete operations in one transaction.
+// In atomic mode (the default) a single failure, validation included, leaves the
+// catalogue untouched and answers 422. In best_effort mode failing operations are
+// reported and the others are applied.
+func (a *application) batchMoviesHandler(c *gin.Context) {
+	var input batchMoviesInput
+	if err := a.bindJSON(c, &input); err != nil {
+		a.bodyErrorResponse(c, err)
+		return
+	}
+	atomic := input.Mode != batchBestEffort
+
+	ops := make([]*data.BatchOp, len(input.Operations))
+	results := make([]*batchOpResult, len(input.Operations))
+	invalid := false
+	for i, in := range input.Operations {
+		op, errs := a.batchOp(in)
+		ops[i] = op
+		results[i] = &batchOpResult{Index: i, Op: in.Op}
+		if errs != nil {
+			invalid = true
+			results[i].Status = "failed"
+			results[i].Errors = errs
+			// Keeps Batch from running the operation
+			op.Err = errInvalidOp
+		}
+	}
+
+	if !(atomic && invalid) {
+		if err := a.models.Movies.Batch(ops, atomic); err != nil {
+			a.dataErrorResponse(c, err, "Error while running batch")
+			return
+		}
+	}
+	failed := invalid
+	for i, op := range ops {
+		if results[i].Errors != nil {
+			continue
+		}
+		if op.Err != nil {
+			failed = true
+			results[i].Status = "failed"
+			results[i].Error = op.Err.Error()
+			continue
+		}
+		results[i].Status = "ok"
+		results[i].Movie = op.Movie
+	}
+	if atomic && failed {
+		// Nothing was applied, say so for every operation which didn't fail
+		for _, r := range results {
+			if r.Status != "failed" {
+				r.Status = "not_applied"
+				r.Movie = nil
+			}
+		}
+		p := a.newProblem(c, http.StatusUnprocessableEntity, "the batch failed, none of its operations was applied")
+		p.Extensions = envelope{"mode": batchAtomic, "results": results}
+		a.writeProblem(c, p)
+		return
+	}
+	mode := batchBestEffort
+	if atomic {
+		mode = batchAtomic
+	}
+	c.JSON(http.StatusOK, envelope{"mode": mode, "results": results})
+}
@@ -0,0 +1,206 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"mdb/internal/data"
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+)
+
+// readCollection reads the collection from the :id param. On failure the
+// response is already written and nil is returned.
+func (a *application) readCollection(c *gin.Context) *data.Collection {
+	id, err := strconv.ParseInt(c.Param("id"), 10, 64)
+	if err != nil || id < 1 {
+		a.errorResponse(c, http.StatusBadRequest, fmt.Errorf("invalid id"), "Id should be a valid integer greater than 0")
+		return nil
+	}
+	collection, err := a.models.Collection.Get(id)
+	if err != nil {
+		a.dataErrorResponse(c, err, "Error while reading collection")
+		return nil
+	}
+	return collection
+}
+
+// writeCollectionWithMovies writes the collection along with its movies in format,
+// as returned by movieFormat.
+func (a *application) writeCollectionWithMovies(c *gin.Context, format string, collection *data.Collection) {
+	mvs, err := a.models.Collection.GetMovies(collection.ID)
+	if err != nil {
+		a.dataErrorResponse(c, err, "Error while reading movies of collection")
+		return
+	}
+	a.renderMovies(c, format, http.StatusOK, envelope{"collection": collection}, nil, mvs)
+}
+
+type createCollectionInput struct {
+	Name        string `json:"name" binding:"required,min=1,max=255"`
+	Description string `json:"description" binding:"max=2000"`
+}
+
+func (a *application) createCollectionHandler(c *gin.Context) {
+	var input createCollectionInput
+	if err := a.bindJSON(c, &input); err != nil {
+		a.bodyErrorResponse(c, err)
+		return
+	}
+	collection := &data.Collection{Name: input.Name, Description: input.Description}
+	if err := a.models.Collection.Insert(collection); err != nil {
+		a.dataErrorResponse(c, err, "Error while inserting collection")
+		return
+	}
+	c.Header("Location", fmt.Sprintf("/v1/collections/%d", collection.ID))
+	c.JSON(http.StatusCreated, envelope{"collection": collection})
+}
+
+func (a *application) listCollectionsHandler(c *gin.Context) {
+	collections, err := a.models.Collection.GetAll()
+	if err != nil {
+		a.dataErrorResponse(c, err, "Error while reading collections")
+		return
+	}
+	c.JSON(http.StatusOK, envelope{"collections": collections})
+}
+
+func (a *application) showCollectionHandler(c *gin.Context) {
+	format, ok := a.movieFormat(c)
+	if !ok {
+		return
+	}
+	collection := a.readCollection(c)
+	if collection == nil {
+		return
+	}
+	a.writeCollectionWithMovies(c, format, collection)
+}
+
+type updateCollectionInput struct {
+	Name        *string `json:"name" binding:"omitempty,min=1,max=255"`
+	Description *string `json:"description" binding:"omitempty,max=2000"`
+}
+
+func (a *application) updateCollectionHandler(c *gin.Context) {
+	collection := a.readCollection(c)
+	if collection == nil {
+		return
+	}
+	var input updateCollectionInput
+	if err := a.bindJSON(c, &input); err != nil {
+		a.bodyErrorResponse(c, err)
+		return
+	}
+	if input.Name != nil {
+		collection.Name = *input.Name
+	}
+	if input.Description != nil {
+		collection.Description = *input.Description
+	}
+	if err := a.models.Collection.Update(collection); err != nil {
+		a.dataErrorResponse(c, err, "Error while updating collection")
+		return
+	}
+	c.JSON(http.StatusOK, envelope{"collection": collection})
+}
+
+func (a *application) deleteCollectionHandler(c *gin.Context) {
+	collection := a.readCollection(c)
+	if collection == nil {
+		return
+	}
+	if err := a.models.Collection.Delete(collection.ID); err != nil {
+		a.dataErrorResponse(c, err, "Error while deleting collection")
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"message": fmt.Sprintf("Collection with ID:%d deleted.", collection.ID)})
+}
+
+type addCollectionMovieInput struct {
+	MovieID int64 `json:"movie_id" binding:"required,min=1"`
+}
+
+func (a *application) addCollectionMovieHandler(c *gin.Context) {
+	format, ok := a.movieFormat(c)
+	if !ok {
+		return
+	}
+	collection := a.readCollection(c)
+	if collection == nil {
+		return
+	}
+	var input addCollectionMovieInput
+	if err := a.bindJSON(c, &input); err != nil {
+		a.bodyErrorResponse(c, err)
+		return
+	}
+	if _, err := a.models.Movies.Get(input.MovieID); err != nil {
+		if errors.Is(err, data.ErrRecordNotFound) {
+			a.errorResponse(c, http.StatusNotFound, err, "movie does not exist")
+			return
+		}
+		a.dataErrorResponse(c, err, "Error while reading movie")
+		return
+	}
+	if err := a.models.Collection.AddMovie(collection.ID, input.MovieID); err != nil {
+		a.dataErrorResponse(c, err, "Error while adding movie to collection")
+		return
+	}
+	a.writeCollectionWithMovies(c, format, collection)
+}
+
+func (a *application) removeCollectionMovieHandler(c *gin.Context) {
+	format, ok := a.movieFormat(c)
+	if !ok {
+		return
+	}
+	collection := a.readCollection(c)
+	if collection == nil {
+		return
+	}
+	movieID, err := strconv.ParseInt(c.Param("movie_id"), 10, 64)
+	if err != nil || movieID < 1 {
+		a.errorResponse(c, http.StatusBadRequest, fmt.Errorf("invalid movie id"), "Movie id should be a valid integer greater than 0")
+		return
+	}
+	if err := a.models.Collection.RemoveMovie(collection.ID, movieID); err != nil {
+		if errors.Is(err, data.ErrRecordNotFound) {
+			a.errorResponse(c, http.StatusNotFound, err, "movie is not part of the collection")
+			return
+		}
+		a.dataErrorResponse(c, err, "Error while removing movie from collection")
+		return
+	}
+	a.writeCollectionWithMovies(c, format, collection)
+}
+
+type reorderCollectionMoviesInput struct {
+	MovieIDs []int64 `json:"movie_ids" binding:"required,unique"`
+}
+
+func (a *application) reorderCollectionMoviesHandler(c *gin.Context) {
+	format, ok := a.movieFormat(c)
+	if !ok {
+		return
+	}
+	collection := a.readCollection(c)
+	if collection == nil {
+		return
+	}
+	var input reorderCollectionMoviesInput
+	if err := a.bindJSON(c, &input); err != nil {
+		a.bodyErrorResponse(c, err)
+		return
+	}
+	if err := a.models.Collection.Reorder(collection.ID, input.MovieIDs); err != nil {
+		if errors.Is(err, data.ErrInvalidOrder) {
+			a.errorResponse(c, http.StatusUnprocessableEntity, err, "")
+			return
+		}
+		a.dataErrorResponse(c, err, "Error while reordering collection")
+		return
+	}
+	a.writeCollectionWithMovies(c, format, collection)
+}
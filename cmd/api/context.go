@@ -8,7 +8,10 @@ import (
 
 type contextKey string
 
-const userContextKey = contextKey("user")
+const (
+	userContextKey      = contextKey("user")
+	requestIDContextKey = contextKey("request_id")
+)
 
 func (a *application) contextSetUser(c *gin.Context, user *data.User) {
 	c.Set(string(userContextKey), user)
@@ -25,3 +28,7 @@ func (a *application) contextGetUser(c *gin.Context) *data.User {
 	}
 	return nil
 }
+
+func (a *application) contextGetRequestID(c *gin.Context) string {
+	return c.GetString(string(requestIDContextKey))
+}
@@ -1,34 +1,140 @@
 package main
 
 import (
+	"encoding/json"
+	"errors"
 	"fmt"
+	"mdb/internal/data"
 	"net/http"
 
 	"github.com/gin-gonic/gin"
 )
 
-type errType struct {
-	Err string `json:"err"`
-	Msg string `json:"msg,omitempty"`
+// Every error response is a RFC 7807 problem, see
+// https://www.rfc-editor.org/rfc/rfc7807
+const problemContentType = "application/problem+json"
+
+// Problem types, problems without a specific type use about:blank and are fully
+// described by their status.
+const (
+	problemAboutBlank = "about:blank"
+	problemValidation = "/problems/validation-error"
+)
+
+// Detail of server errors, their cause is only logged
+const serverErrorDetail = "the server encountered a problem and could not process your request"
+
+type problem struct {
+	Type      string            `json:"type"`
+	Title     string            `json:"title"`
+	Status    int               `json:"status"`
+	Detail    string            `json:"detail,omitempty"`
+	Instance  string            `json:"instance,omitempty"`
+	RequestID string            `json:"request_id,omitempty"`
+	Errors    map[string]string `json:"errors,omitempty"`
+	// Extension members, written next to the standard ones
+	Extensions envelope `json:"-"`
 }
 
-func (a *application) createError(e error, msg string) errType {
-	et := errType{
-		Err: e.Error(),
-		Msg: msg,
+func (p problem) MarshalJSON() ([]byte, error) {
+	type plain problem
+	js, err := json.Marshal(plain(p))
+	if err != nil || len(p.Extensions) == 0 {
+		return js, err
+	}
+	out := map[string]any{}
+	for k, v := range p.Extensions {
+		out[k] = v
+	}
+	var members map[string]json.RawMessage
+	if err := json.Unmarshal(js, &members); err != nil {
+		return nil, err
+	}
+	for k, v := range members {
+		out[k] = v
 	}
-	a.logger.PrintError(e, map[string]string{"msg": msg})
-	return et
+	return json.Marshal(out)
+}
+
+func (a *application) newProblem(c *gin.Context, status int, detail string) problem {
+	return problem{
+		Type:      problemAboutBlank,
+		Title:     http.StatusText(status),
+		Status:    status,
+		Detail:    detail,
+		Instance:  c.Request.URL.Path,
+		RequestID: a.contextGetRequestID(c),
+	}
+}
+
+// writeProblem sends p and aborts the handler chain.
+func (a *application) writeProblem(c *gin.Context, p problem) {
+	c.Header("Content-Type", problemContentType)
+	c.AbortWithStatusJSON(p.Status, p)
+}
+
+// errorResponse reports err with the given status. detail tells the client what
+// went wrong, it defaults to err for client errors. Server errors never show err
+// to the client, it is logged instead.
+func (a *application) errorResponse(c *gin.Context, status int, err error, detail string) {
+	a.writeProblem(c, a.errorProblem(c, status, err, detail))
+}
+
+func (a *application) errorProblem(c *gin.Context, status int, err error, detail string) problem {
+	if status >= http.StatusInternalServerError {
+		if err != nil {
+			a.logger.PrintError(err, map[string]string{"msg": detail, "request_id": a.contextGetRequestID(c)})
+		}
+		if detail == "" {
+			detail = serverErrorDetail
+		}
+	} else if detail == "" && err != nil {
+		detail = err.Error()
+	}
+	return a.newProblem(c, status, detail)
+}
+
+// dataErrorStatus maps the sentinel errors of the data package to a status, any
+// other error is a server error.
+func dataErrorStatus(err error) int {
+	switch {
+	case errors.Is(err, data.ErrRecordNotFound):
+		return http.StatusNotFound
+	case errors.Is(err, data.ErrEditConflict):
+		return http.StatusConflict
+	case errors.Is(err, data.ErrDuplicate):
+		return http.StatusUnprocessableEntity
+	default:
+		return http.StatusInternalServerError
+	}
+}
+
+// dataErrorResponse reports an error returned by the models with the status of
+// dataErrorStatus. detail is only used for server errors, the others are
+// described by err itself.
+func (a *application) dataErrorResponse(c *gin.Context, err error, detail string) {
+	status := dataErrorStatus(err)
+	if status != http.StatusInternalServerError {
+		detail = ""
+	}
+	a.errorResponse(c, status, err, detail)
+}
+
+// failedValidationResponse reports the per-field errors of validation.Errors.
+func (a *application) failedValidationResponse(c *gin.Context, status int, errs map[string]string) {
+	p := a.newProblem(c, status, "the request has invalid fields")
+	p.Type = problemValidation
+	p.Errors = errs
+	a.writeProblem(c, p)
 }
 
 func (a *application) noRouteHandler(c *gin.Context) {
-	e := a.createError(fmt.Errorf("no route"), "")
-	c.JSON(http.StatusNotFound, e)
+	a.errorResponse(c, http.StatusNotFound, fmt.Errorf("no route"), "the requested resource could not be found")
 }
 
 func (a *application) noMethodHandler(c *gin.Context) {
-	e := a.createError(fmt.Errorf("no method found"), "")
-	c.JSON(http.StatusNotFound, e)
+	a.errorResponse(c, http.StatusMethodNotAllowed, fmt.Errorf("no method found"),
+		fmt.Sprintf("the %v method is not supported for this resource", c.Request.Method))
 }
 
 func (a *application) authRequiredError(c *gin.Context, msg ...string) {
@@ -36,7 +142,7 @@ func (a *application) authRequiredError(c *gin.Context, msg ...string) {
 	if len(msg) != 0 {
 		m = msg[0]
 	}
-	c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{"error": m})
+	a.errorResponse(c, http.StatusUnauthorized, nil, m)
 }
 
 func (a *application) inactiveAccountError(c *gin.Context, msg ...string) {
@@ -44,7 +150,7 @@ func (a *application) inactiveAccountError(c *gin.Context, msg ...string) {
 	if len(msg) != 0 {
 		m = msg[0]
 	}
-	c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{"error": m})
+	a.errorResponse(c, http.StatusUnauthorized, nil, m)
 }
 
 func (a *application) noPermitError(c *gin.Context, msg ...string) {
@@ -52,5 +158,5 @@ func (a *application) noPermitError(c *gin.Context, msg ...string) {
 	if len(msg) != 0 {
 		m = msg[0]
 	}
-	c.AbortWithStatusJSON(http.StatusForbidden, gin.H{"error": m})
+	a.errorResponse(c, http.StatusForbidden, nil, m)
 }
@@ -0,0 +1,161 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"mdb/internal/data"
+	"mdb/internal/validation"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+)
+
+// loadGenres refreshes the vocabulary used by the "genre" validation, it has to be
+// called after every change to the genres table.
+func (a *application) loadGenres() error {
+	genres, err := a.models.Genre.GetAll()
+	if err != nil {
+		return err
+	}
+	a.genres.Load(genres)
+	return nil
+}
+
+// genreChanged reloads the vocabulary and writes the current list of genres.
+func (a *application) genreChanged(c *gin.Context, status int) {
+	if err := a.loadGenres(); err != nil {
+		a.dataErrorResponse(c, err, "Error while reloading genres")
+		return
+	}
+	genres, err := a.models.Genre.GetAll()
+	if err != nil {
+		a.dataErrorResponse(c, err, "Error while reading genres")
+		return
+	}
+	c.JSON(status, envelope{"genres": genres})
+}
+
+func (a *application) genreError(c *gin.Context, err error) {
+	switch {
+	case errors.Is(err, data.ErrRecordNotFound):
+		a.errorResponse(c, http.StatusNotFound, err, "")
+	case errors.Is(err, data.ErrDuplicateGenre), errors.Is(err, data.ErrGenreInUse):
+		a.errorResponse(c, http.StatusConflict, err, "")
+	default:
+		a.dataErrorResponse(c, err, "Error while updating genres")
+	}
+}
+
+func (a *application) listGenresHandler(c *gin.Context) {
+	genres, err := a.models.Genre.GetAll()
+	if err != nil {
+		a.dataErrorResponse(c, err, "Error while reading genres")
+		return
+	}
+	c.JSON(http.StatusOK, envelope{"genres": genres})
+}
+
+type createGenreInput struct {
+	Slug    string   `json:"slug" binding:"required,min=1,max=64"`
+	Name    string   `json:"name" binding:"required,min=1,max=255"`
+	Aliases []string `json:"aliases" binding:"omitempty,unique,dive,min=1,max=64"`
+}
+
+func (a *application) createGenreHandler(c *gin.Context) {
+	var input createGenreInput
+	if err := a.bindJSON(c, &input); err != nil {
+		a.bodyErrorResponse(c, err)
+		return
+	}
+	genre := &data.Genre{
+		Slug: validation.GenreKey(input.Slug),
+		Name: input.Name,
+	}
+	for _, alias := range input.Aliases {
+		genre.Aliases = append(genre.Aliases, validation.GenreKey(alias))
+	}
+	if err := a.models.Genre.Insert(genre); err != nil {
+		a.genreError(c, err)
+		return
+	}
+	a.genreChanged(c, http.StatusCreated)
+}
+
+type updateGenreInput struct {
+	Name string `json:"name" binding:"required,min=1,max=255"`
+}
+
+func (a *application) updateGenreHandler(c *gin.Context) {
+	var input updateGenreInput
+	if err := a.bindJSON(c, &input); err != nil {
+		a.bodyErrorResponse(c, err)
+		return
+	}
+	genre := &data.Genre{Slug: validation.GenreKey(c.Param("slug")), Name: input.Name}
+	if err := a.models.Genre.Update(genre); err != nil {
+		a.genreError(c, err)
+		return
+	}
+	a.genreChanged(c, http.StatusOK)
+}
+
+func (a *application) deleteGenreHandler(c *gin.Context) {
+	slug := validation.GenreKey(c.Param("slug"))
+	if err := a.models.Genre.Delete(slug); err != nil {
+		a.genreError(c, err)
+		return
+	}
+	a.genreChanged(c, http.StatusOK)
+}
+
+type addGenreAliasInput struct {
+	Alias string `json:"alias" binding:"required,min=1,max=64"`
+}
+
+func (a *application) addGenreAliasHandler(c *gin.Context) {
+	var input addGenreAliasInput
+	if err := a.bindJSON(c, &input); err != nil {
+		a.bodyErrorResponse(c, err)
+		return
+	}
+	slug := validation.GenreKey(c.Param("slug"))
+	if err := a.models.Genre.AddAlias(slug, validation.GenreKey(input.Alias)); err != nil {
+		a.genreError(c, err)
+		return
+	}
+	a.genreChanged(c, http.StatusCreated)
+}
+
+func (a *application) removeGenreAliasHandler(c *gin.Context) {
+	slug := validation.GenreKey(c.Param("slug"))
+	if err := a.models.Genre.RemoveAlias(slug, validation.GenreKey(c.Param("alias"))); err != nil {
+		a.genreError(c, err)
+		return
+	}
+	a.genreChanged(c, http.StatusOK)
+}
+
+type mergeGenreInput struct {
+	Into string `json:"into" binding:"required,min=1,max=64"`
+}
+
+// mergeGenreHandler folds the genre of the URL into the genre given in the body,
+// e.g. POST /v1/genres/science-fiction/merge {"into": "sci-fi"}.
+func (a *application) mergeGenreHandler(c *gin.Context) {
+	var input mergeGenreInput
+	if err := a.bindJSON(c, &input); err != nil {
+		a.bodyErrorResponse(c, err)
+		return
+	}
+	from := validation.GenreKey(c.Param("slug"))
+	into := validation.GenreKey(input.Into)
+	if from == into {
+		a.errorResponse(c, http.StatusBadRequest, fmt.Errorf("invalid merge"), "a genre can't be merged into itself")
+		return
+	}
+	if err := a.models.Genre.Merge(from, into); err != nil {
+		a.genreError(c, err)
+		return
+	}
+	a.genreChanged(c, http.StatusOK)
+}
@@ -1,6 +1,7 @@
 package main
 
 import (
+	"encoding/json"
 	"mdb/internal/data"
 	"strings"
 )
@@ -13,15 +14,62 @@ func addDefaultValue(lm *data.ListMovie) {
 	} else {
 		lm.Genres = strings.Split(lm.Genres[0], ",")
 	}
-	if lm.Page == 0 {
-		lm.Page = 1
+	if lm.Facets != nil {
+		lm.Facets = strings.Split(strings.Join(lm.Facets, ","), ",")
 	}
-	if lm.PageSize == 0 {
-		lm.PageSize = 2
+	lm.Fields = splitFields(lm.Fields)
+	if lm.GenreMatch == "" {
+		lm.GenreMatch = "all"
 	}
-	if lm.Sort == "" {
-		lm.Sort = "id"
+	addDefaultFilters(&lm.Filters)
+}
+
+func addDefaultFilters(f *data.Filters) {
+	if f.Page == 0 {
+		f.Page = 1
+	}
+	if f.PageSize == 0 {
+		f.PageSize = 2
+	}
+	if f.Sort == "" {
+		f.Sort = "id"
+	}
+}
+
+// splitFields turns fields=id,title into one field per item.
+func splitFields(fields []string) []string {
+	if fields == nil {
+		return nil
+	}
+	return strings.Split(strings.Join(fields, ","), ",")
+}
+
+// onlyFields renders the movies keeping only the given JSON keys, they are returned
+// untouched when fields is empty.
+func onlyFields(fields []string, movies ...*data.Movie) ([]any, error) {
+	out := make([]any, len(movies))
+	for i, m := range movies {
+		if len(fields) == 0 {
+			out[i] = m
+			continue
+		}
+		js, err := json.Marshal(m)
+		if err != nil {
+			return nil, err
+		}
+		var all map[string]json.RawMessage
+		if err := json.Unmarshal(js, &all); err != nil {
+			return nil, err
+		}
+		kept := map[string]json.RawMessage{}
+		for _, f := range fields {
+			if v, ok := all[f]; ok {
+				kept[f] = v
+			}
+		}
+		out[i] = kept
 	}
+	return out, nil
 }
 
 func (a *application) Background(fn func()) {
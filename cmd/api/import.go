@@ -0,0 +1,243 @@
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/csv"
+	"errors"
+	"fmt"
+	"io"
+	"mdb/internal/data"
+	"mdb/internal/validation"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"github.com/gin-gonic/gin/binding"
+)
+
+// Number of movies inserted per transaction
+const importBatchSize = 100
+
+type importRow struct {
+	Row    int               `json:"row"`
+	Status string            `json:"status"`
+	ID     int64             `json:"id,omitempty"`
+	Errors map[string]string `json:"errors,omitempty"`
+}
+
+// rowFunc receives every row of an import, along with its line number in the
+// upload. errs is set when the row could not be turned into a movie.
+type rowFunc func(line int, movie *data.Movie, errs map[string]string) error
+
+// readCSVMovies reads a CSV upload with a header naming the title, year, runtime
+// and genres columns. Runtime is either "102 mins" or "102" and genres are comma
+// separated within their field, e.g. "drama,sci-fi".
+func readCSVMovies(r io.Reader, fn rowFunc) error {
+	cr := csv.NewReader(r)
+	header, err := cr.Read()
+	if err != nil {
+		return fmt.Errorf("unable to read CSV header: %w", err)
+	}
+	cols := map[string]int{}
+	for i, h := range header {
+		cols[strings.ToLower(strings.TrimSpace(h))] = i
+	}
+	for _, name := range []string{"title", "year", "runtime", "genres"} {
+		if _, ok := cols[name]; !ok {
+			return fmt.Errorf("CSV header should have a %v column", name)
+		}
+	}
+	for {
+		record, err := cr.Read()
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			var pe *csv.ParseError
+			if errors.As(err, &pe) {
+				if err := fn(pe.StartLine, nil, map[string]string{"other": pe.Error()}); err != nil {
+					return err
+				}
+				continue
+			}
+			return err
+		}
+		line, _ := cr.FieldPos(0)
+		movie, errs := csvMovie(record, cols)
+		if err := fn(line, movie, errs); err != nil {
+			return err
+		}
+	}
+}
+
+func csvMovie(record []string, cols map[string]int) (*data.Movie, map[string]string) {
+	movie := &data.Movie{Title: strings.TrimSpace(record[cols["title"]])}
+	errs := map[string]string{}
+	if v := strings.TrimSpace(record[cols["year"]]); v != "" {
+		year, err := strconv.ParseInt(v, 10, 32)
+		if err != nil {
+			errs["Year"] = "Should be an integer"
+		}
+		movie.Year = int32(year)
+	}
+	if v := strings.TrimSpace(record[cols["runtime"]]); v != "" {
+		if !strings.HasSuffix(v, " mins") {
+			v += " mins"
+		}
+		// Same parsing as the runtime of a JSON body
+		if err := movie.Runtime.UnmarshalJSON([]byte(strconv.Quote(v))); err != nil {
+			errs["Runtime"] = err.Error()
+		}
+	}
+	if v := strings.TrimSpace(record[cols["genres"]]); v != "" {
+		for _, g := range strings.Split(v, ",") {
+			movie.Genres = append(movie.Genres, strings.TrimSpace(g))
+		}
+	}
+	if len(errs) > 0 {
+		return nil, errs
+	}
+	return movie, nil
+}
+
+// readNDJSONMovies reads one JSON movie per line, blank lines are ignored.
+func readNDJSONMovies(r io.Reader, fn rowFunc) error {
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 64*1024), 1024*1024)
+	line := 0
+	for scanner.Scan() {
+		line++
+		if strings.TrimSpace(scanner.Text()) == "" {
+			continue
+		}
+		var movie data.Movie
+		if err := decodeStrict(bytes.NewReader(scanner.Bytes()), &movie); err != nil {
+			if err := fn(line, nil, validation.Errors(err)); err != nil {
+				return err
+			}
+			continue
+		}
+		if err := fn(line, &movie, nil); err != nil {
+			return err
+		}
+	}
+	return scanner.Err()
+}
+
+type importMoviesInput struct {
+	DryRun bool `form:"dry_run"`
+}
+
+// importMoviesHandler loads movies from a CSV (text/csv) or NDJSON
+// (application/x-ndjson) upload. Every row is validated like the body of
+// createMovieHandler and valid rows are inserted by batches. With dry_run=true
+// nothing is written but the report tells what would have happened. Uploads are
+// limited to the configured import size, the report of a larger one stops with a
+// 413.
+func (a *application) importMoviesHandler(c *gin.Context) {
+	var input importMoviesInput
+	if err := c.ShouldBindQuery(&input); err != nil {
+		a.failedValidationResponse(c, http.StatusBadRequest, validation.Errors(err))
+		return
+	}
+	var read func(io.Reader, rowFunc) error
+	switch c.ContentType() {
+	case "text/csv":
+		read = readCSVMovies
+	case "application/x-ndjson", "application/ndjson":
+		read = readNDJSONMovies
+	default:
+		a.errorResponse(c, http.StatusUnsupportedMediaType, fmt.Errorf("unsupported content type %q", c.ContentType()), "use text/csv or application/x-ndjson")
+		return
+	}
+
+	report := []*importRow{}
+	var batch []*data.Movie
+	var batchRows []*importRow
+	var dbErr error
+	// Every batch of a dry run is rolled back, so a movie which a previous batch
+	// would have created is unknown to the next ones. It is remembered here by
+	// lowercased title and year, like the duplicate check of InsertBatch.
+	dryRunCreated := map[string]bool{}
+	dryRunKey := func(m *data.Movie) string {
+		return fmt.Sprintf("%v\x00%d", strings.ToLower(m.Title), m.Year)
+	}
+	flush := func() error {
+		if len(batch) == 0 {
+			return nil
+		}
+		results, err := a.models.Movies.InsertBatch(batch, input.DryRun)
+		if err != nil {
+			dbErr = err
+			return err
+		}
+		for i, res := range results {
+			batchRows[i].Status = res.Status
+			// A dry run hands out ids which were rolled back
+			if res.Status == data.ImportCreated {
+				if input.DryRun {
+					dryRunCreated[dryRunKey(batch[i])] = true
+				} else {
+					batchRows[i].ID = batch[i].ID
+				}
+			}
+			if res.Err != nil {
+				batchRows[i].Errors = map[string]string{"other": res.Err.Error()}
+			}
+		}
+		batch, batchRows = nil, nil
+		return nil
+	}
+	body := http.MaxBytesReader(c.Writer, c.Request.Body, a.config.body.importMaxBytes)
+	err := read(body, func(line int, movie *data.Movie, errs map[string]string) error {
+		row := &importRow{Row: line}
+		report = append(report, row)
+		if errs == nil {
+			if err := binding.Validator.ValidateStruct(movie); err != nil {
+				errs = validation.Errors(err)
+			}
+		}
+		if errs != nil {
+			row.Status = data.ImportFailed
+			row.Errors = errs
+			return nil
+		}
+		movie.Genres = a.genres.Normalize(movie.Genres)
+		if input.DryRun && dryRunCreated[dryRunKey(movie)] {
+			row.Status = data.ImportSkipped
+			return nil
+		}
+		batch = append(batch, movie)
+		batchRows = append(batchRows, row)
+		if len(batch) == importBatchSize {
+			return flush()
+		}
+		return nil
+	})
+	if err == nil {
+		err = flush()
+	}
+	if err != nil {
+		status := http.StatusBadRequest
+		msg := "import stopped, rows of the previous batches were processed"
+		switch {
+		case dbErr != nil:
+			status = http.StatusInternalServerError
+		case tooLarge(err):
+			status = http.StatusRequestEntityTooLarge
+			msg = fmt.Sprintf("body must not be larger than %d bytes, %v", a.config.body.importMaxBytes, msg)
+		}
+		p := a.errorProblem(c, status, err, msg)
+		p.Extensions = envelope{"rows": report}
+		a.writeProblem(c, p)
+		return
+	}
+
+	summary := map[string]int{data.ImportCreated: 0, data.ImportSkipped: 0, data.ImportFailed: 0}
+	for _, row := range report {
+		summary[row.Status]++
+	}
+	c.JSON(http.StatusOK, envelope{"dry_run": input.DryRun, "summary": summary, "rows": report})
+}
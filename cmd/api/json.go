@@ -0,0 +1,119 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"mdb/internal/validation"
+	"net/http"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"github.com/gin-gonic/gin/binding"
+)
+
+// bodyError is a request body which could not be decoded, as opposed to a decoded
+// body failing validation.
+type bodyError struct {
+	status int
+	msg    string
+}
+
+func (e *bodyError) Error() string {
+	return e.msg
+}
+
+// decodeStrict decodes exactly one JSON value from r into dst. Unknown fields and
+// anything after the value are rejected and decoding errors are turned into a
+// bodyError telling where the body went wrong.
+func decodeStrict(r io.Reader, dst any) error {
+	dec := json.NewDecoder(r)
+	dec.DisallowUnknownFields()
+	if err := dec.Decode(dst); err != nil {
+		return decodeError(err)
+	}
+	if err := dec.Decode(&struct{}{}); err != io.EOF {
+		return &bodyError{http.StatusBadRequest, "body must only contain a single JSON value"}
+	}
+	return nil
+}
+
+func decodeError(err error) error {
+	var syntaxError *json.SyntaxError
+	var typeError *json.UnmarshalTypeError
+	switch {
+	case errors.As(err, &syntaxError):
+		return &bodyError{http.StatusBadRequest, fmt.Sprintf("body contains badly-formed JSON (at byte %d)", syntaxError.Offset)}
+	case errors.Is(err, io.ErrUnexpectedEOF):
+		return &bodyError{http.StatusBadRequest, "body contains badly-formed JSON"}
+	case errors.As(err, &typeError):
+		if typeError.Field != "" {
+			return &bodyError{http.StatusBadRequest, fmt.Sprintf("body contains incorrect JSON type for field %q, expected %v", typeError.Field, typeError.Type)}
+		}
+		return &bodyError{http.StatusBadRequest, fmt.Sprintf("body contains incorrect JSON type (at byte %d)", typeError.Offset)}
+	case errors.Is(err, io.EOF):
+		return &bodyError{http.StatusBadRequest, "body must not be empty"}
+	case strings.HasPrefix(err.Error(), "json: unknown field "):
+		field := strings.TrimPrefix(err.Error(), "json: unknown field ")
+		return &bodyError{http.StatusBadRequest, fmt.Sprintf("body contains unknown key %v", field)}
+	case tooLarge(err):
+		return &bodyError{http.StatusRequestEntityTooLarge, "body is too large"}
+	default:
+		// e.g. a data.RuntimeErr, left to validation.Errors
+		return err
+	}
+}
+
+// bindJSON is the strict counterpart of c.ShouldBindJSON: the body is limited to
+// the configured size, decoded with decodeStrict and validated with the binding
+// tags of dst.
+func (a *application) bindJSON(c *gin.Context, dst any) error {
+	body := http.MaxBytesReader(c.Writer, c.Request.Body, a.config.body.maxBytes)
+	if err := decodeStrict(body, dst); err != nil {
+		return a.withMaxBytes(err)
+	}
+	return binding.Validator.ValidateStruct(dst)
+}
+
+// readBody reads the whole body, up to the configured size, for handlers which
+// decode it themselves.
+func (a *application) readBody(c *gin.Context) ([]byte, error) {
+	body, err := io.ReadAll(http.MaxBytesReader(c.Writer, c.Request.Body, a.config.body.maxBytes))
+	if err != nil {
+		return nil, a.withMaxBytes(decodeError(err))
+	}
+	return body, nil
+}
+
+// tooLarge tells whether err, or an error it wraps, comes from a body going over
+// the limit of http.MaxBytesReader.
+func tooLarge(err error) bool {
+	for ; err != nil; err = errors.Unwrap(err) {
+		// http.MaxBytesError only exists from Go 1.19
+		if err.Error() == "http: request body too large" {
+			return true
+		}
+	}
+	return false
+}
+
+// withMaxBytes tells the limit in the message of a body which is too large.
+func (a *application) withMaxBytes(err error) error {
+	var be *bodyError
+	if errors.As(err, &be) && be.status == http.StatusRequestEntityTooLarge {
+		be.msg = fmt.Sprintf("body must not be larger than %d bytes", a.config.body.maxBytes)
+	}
+	return err
+}
+
+// bodyErrorResponse reports an error of bindJSON, a body which could not be
+// decoded or the per-field errors of the validation.
+func (a *application) bodyErrorResponse(c *gin.Context, err error) {
+	var be *bodyError
+	if errors.As(err, &be) {
+		a.errorResponse(c, be.status, err, "")
+		return
+	}
+	a.failedValidationResponse(c, http.StatusBadRequest, validation.Errors(err))
+}
@@ -0,0 +1,271 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"mdb/internal/data"
+	"mdb/internal/validation"
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+)
+
+// ownedList reads the list from the :id param and makes sure it belongs to the
+// current user. The :id param can also be the kind of a built-in list, to_watch
+// or watched. A list of another user is reported as not found, so that private
+// lists don't leak their existence. On failure the response is already written.
+func (a *application) ownedList(c *gin.Context) *data.List {
+	if kind := c.Param("id"); kind == data.ListToWatch || kind == data.ListWatched {
+		list, err := a.models.List.GetBuiltIn(a.contextGetUser(c).ID, kind)
+		if err != nil {
+			a.dataErrorResponse(c, err, "Error while reading list")
+			return nil
+		}
+		return list
+	}
+	id, err := strconv.ParseInt(c.Param("id"), 10, 64)
+	if err != nil || id < 1 {
+		a.errorResponse(c, http.StatusBadRequest, fmt.Errorf("invalid id"), "Id should be a valid integer greater than 0, to_watch or watched")
+		return nil
+	}
+	list, err := a.models.List.Get(id)
+	if err != nil {
+		a.dataErrorResponse(c, err, "Error while reading list")
+		return nil
+	}
+	if list.UserID != a.contextGetUser(c).ID {
+		a.errorResponse(c, http.StatusNotFound, data.ErrRecordNotFound, "")
+		return nil
+	}
+	return list
+}
+
+// listPageInput is the query of the routes showing a list, its movies keep the
+// order of the list so only the page can be chosen.
+type listPageInput struct {
+	Page     int `form:"page" binding:"omitempty,min=1,max=10000"`
+	PageSize int `form:"page_size" binding:"omitempty,min=1,max=100"`
+}
+
+// writeListWithMovies writes the list along with a page of its movies in format,
+// as returned by movieFormat.
+func (a *application) writeListWithMovies(c *gin.Context, format string, list *data.List) {
+	var input listPageInput
+	if err := c.ShouldBindQuery(&input); err != nil {
+		a.failedValidationResponse(c, http.StatusBadRequest, validation.Errors(err))
+		return
+	}
+	filters := data.Filters{Page: input.Page, PageSize: input.PageSize}
+	addDefaultFilters(&filters)
+	mvs, md, err := a.models.List.GetMovies(list.ID, filters)
+	if err != nil {
+		a.dataErrorResponse(c, err, "Error while reading movies of list")
+		return
+	}
+	a.renderMovies(c, format, http.StatusOK, envelope{"list": list, "metadata": md}, nil, mvs)
+}
+
+type createListInput struct {
+	Name   string `json:"name" binding:"required,min=1,max=255"`
+	Public bool   `json:"public"`
+}
+
+func (a *application) createListHandler(c *gin.Context) {
+	var input createListInput
+	if err := a.bindJSON(c, &input); err != nil {
+		a.bodyErrorResponse(c, err)
+		return
+	}
+	list := &data.List{
+		UserID: a.contextGetUser(c).ID,
+		Name:   input.Name,
+		Public: input.Public,
+	}
+	if err := a.models.List.Insert(list); err != nil {
+		a.dataErrorResponse(c, err, "Error while inserting list")
+		return
+	}
+	c.Header("Location", fmt.Sprintf("/v1/users/me/lists/%d", list.ID))
+	c.JSON(http.StatusCreated, envelope{"list": list})
+}
+
+func (a *application) listListsHandler(c *gin.Context) {
+	lists, err := a.models.List.GetAllForUser(a.contextGetUser(c).ID)
+	if err != nil {
+		a.dataErrorResponse(c, err, "Error while reading lists")
+		return
+	}
+	c.JSON(http.StatusOK, envelope{"lists": lists})
+}
+
+func (a *application) showListHandler(c *gin.Context) {
+	format, ok := a.movieFormat(c)
+	if !ok {
+		return
+	}
+	list := a.ownedList(c)
+	if list == nil {
+		return
+	}
+	a.writeListWithMovies(c, format, list)
+}
+
+// showPublicListHandler lets any user read a list which is shared publicly, the
+// owner can always read their own list.
+func (a *application) showPublicListHandler(c *gin.Context) {
+	format, ok := a.movieFormat(c)
+	if !ok {
+		return
+	}
+	id, err := strconv.ParseInt(c.Param("id"), 10, 64)
+	if err != nil || id < 1 {
+		a.errorResponse(c, http.StatusBadRequest, fmt.Errorf("invalid id"), "Id should be a valid integer greater than 0")
+		return
+	}
+	list, err := a.models.List.Get(id)
+	if err != nil {
+		a.dataErrorResponse(c, err, "Error while reading list")
+		return
+	}
+	if !list.Public && list.UserID != a.contextGetUser(c).ID {
+		a.errorResponse(c, http.StatusNotFound, data.ErrRecordNotFound, "")
+		return
+	}
+	a.writeListWithMovies(c, format, list)
+}
+
+type updateListInput struct {
+	Name   *string `json:"name" binding:"omitempty,min=1,max=255"`
+	Public *bool   `json:"public"`
+}
+
+func (a *application) updateListHandler(c *gin.Context) {
+	list := a.ownedList(c)
+	if list == nil {
+		return
+	}
+	var input updateListInput
+	if err := a.bindJSON(c, &input); err != nil {
+		a.bodyErrorResponse(c, err)
+		return
+	}
+	if input.Name != nil && *input.Name != list.Name && list.Kind != data.ListCustom {
+		a.failedValidationResponse(c, http.StatusUnprocessableEntity, map[string]string{"Name": "Built-in lists can't be renamed"})
+		return
+	}
+	if input.Name != nil {
+		list.Name = *input.Name
+	}
+	if input.Public != nil {
+		list.Public = *input.Public
+	}
+	if err := a.models.List.Update(list); err != nil {
+		a.dataErrorResponse(c, err, "Error while updating list")
+		return
+	}
+	c.JSON(http.StatusOK, envelope{"list": list})
+}
+
+func (a *application) deleteListHandler(c *gin.Context) {
+	list := a.ownedList(c)
+	if list == nil {
+		return
+	}
+	if list.Kind != data.ListCustom {
+		a.errorResponse(c, http.StatusConflict, fmt.Errorf("built-in list"), "built-in lists can't be deleted")
+		return
+	}
+	if err := a.models.List.Delete(list.ID); err != nil {
+		a.dataErrorResponse(c, err, "Error while deleting list")
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"message": fmt.Sprintf("List with ID:%d deleted.", list.ID)})
+}
+
+type addListMovieInput struct {
+	MovieID int64 `json:"movie_id" binding:"required,min=1"`
+}
+
+func (a *application) addListMovieHandler(c *gin.Context) {
+	format, ok := a.movieFormat(c)
+	if !ok {
+		return
+	}
+	list := a.ownedList(c)
+	if list == nil {
+		return
+	}
+	var input addListMovieInput
+	if err := a.bindJSON(c, &input); err != nil {
+		a.bodyErrorResponse(c, err)
+		return
+	}
+	if _, err := a.models.Movies.Get(input.MovieID); err != nil {
+		if errors.Is(err, data.ErrRecordNotFound) {
+			a.errorResponse(c, http.StatusNotFound, err, "movie does not exist")
+			return
+		}
+		a.dataErrorResponse(c, err, "Error while reading movie")
+		return
+	}
+	if err := a.models.List.AddMovie(list.ID, input.MovieID); err != nil {
+		a.dataErrorResponse(c, err, "Error while adding movie to list")
+		return
+	}
+	a.writeListWithMovies(c, format, list)
+}
+
+func (a *application) removeListMovieHandler(c *gin.Context) {
+	format, ok := a.movieFormat(c)
+	if !ok {
+		return
+	}
+	list := a.ownedList(c)
+	if list == nil {
+		return
+	}
+	movieID, err := strconv.ParseInt(c.Param("movie_id"), 10, 64)
+	if err != nil || movieID < 1 {
+		a.errorResponse(c, http.StatusBadRequest, fmt.Errorf("invalid movie id"), "Movie id should be a valid integer greater than 0")
+		return
+	}
+	if err := a.models.List.RemoveMovie(list.ID, movieID); err != nil {
+		if errors.Is(err, data.ErrRecordNotFound) {
+			a.errorResponse(c, http.StatusNotFound, err, "movie is not part of the list")
+			return
+		}
+		a.dataErrorResponse(c, err, "Error while removing movie from list")
+		return
+	}
+	a.writeListWithMovies(c, format, list)
+}
+
+type reorderListMoviesInput struct {
+	MovieIDs []int64 `json:"movie_ids" binding:"required,unique"`
+}
+
+func (a *application) reorderListMoviesHandler(c *gin.Context) {
+	format, ok := a.movieFormat(c)
+	if !ok {
+		return
+	}
+	list := a.ownedList(c)
+	if list == nil {
+		return
+	}
+	var input reorderListMoviesInput
+	if err := a.bindJSON(c, &input); err != nil {
+		a.bodyErrorResponse(c, err)
+		return
+	}
+	if err := a.models.List.Reorder(list.ID, input.MovieIDs); err != nil {
+		if errors.Is(err, data.ErrInvalidOrder) {
+			a.errorResponse(c, http.StatusUnprocessableEntity, err, "")
+			return
+		}
+		a.dataErrorResponse(c, err, "Error while reordering list")
+		return
+	}
+	a.writeListWithMovies(c, format, list)
+}
@@ -2,6 +2,7 @@ package main
 
 import (
 	"context"
+	"crypto/rand"
 	"database/sql"
 	"flag"
 	"fmt"
@@ -10,6 +11,7 @@ import (
 	"mdb/internal/data"
 	"mdb/internal/jsonlog"
 	"mdb/internal/mailer"
+	"mdb/internal/validation"
 	"os"
 	"sync"
 	"time"
@@ -34,6 +36,16 @@ type config struct {
 		rps    float64
 		burst  int
 	}
+	cursor struct {
+		secret string
+	}
+	stats struct {
+		ttl time.Duration
+	}
+	body struct {
+		maxBytes       int64
+		importMaxBytes int64
+	}
 	smtp struct {
 		host     string
 		port     int
@@ -48,6 +60,7 @@ type application struct {
 	logger *jsonlog.Logger
 	models data.Models
 	mailer mailer.Mailer
+	genres *validation.Vocabulary
 	wg     sync.WaitGroup
 }
 
@@ -64,6 +77,10 @@ func main() {
 	flag.IntVar(&cfg.limiter.burst, "limiter-brust", 4, "Rate Limiter max brust")
 	flag.Float64Var(&cfg.limiter.rps, "limiter-rps", 2, "Rate Limiter max rate per minute")
 	flag.BoolVar(&cfg.limiter.enable, "limiter-enabled", true, "Enable rate limiter")
+	flag.StringVar(&cfg.cursor.secret, "cursor-secret", "", "Key signing pagination cursors, random when empty")
+	flag.DurationVar(&cfg.stats.ttl, "stats-ttl", 5*time.Minute, "How long catalogue statistics are cached")
+	flag.Int64Var(&cfg.body.maxBytes, "body-max-bytes", 1_048_576, "Largest JSON request body accepted, in bytes")
+	flag.Int64Var(&cfg.body.importMaxBytes, "import-max-bytes", 32*1_048_576, "Largest movie import accepted, in bytes")
 	flag.StringVar(&cfg.smtp.host, "smtp-host", "127.0.0.1", "SMTP host")
 	flag.IntVar(&cfg.smtp.port, "smtp-port", 25, "SMTP port")
 	flag.StringVar(&cfg.smtp.username, "smtp-username", "", "SMTP username")
@@ -87,11 +104,23 @@ func main() {
 		log.Fatalln(errf.Error())
 	}
 	jLogger := jsonlog.New([]io.Writer{os.Stdout, logf}, jsonlog.LevelInfo)
+	cursorKey := []byte(cfg.cursor.secret)
+	if len(cursorKey) == 0 {
+		// Cursors won't survive a restart nor be shared between instances
+		cursorKey = make([]byte, 32)
+		if _, err := rand.Read(cursorKey); err != nil {
+			log.Fatal(err)
+		}
+	}
 	app := &application{
 		config: cfg,
 		logger: jLogger,
-		models: data.NewModel(db),
+		models: data.NewModel(db, cursorKey, cfg.stats.ttl),
 		mailer: mailer.New(cfg.smtp.host, cfg.smtp.port, cfg.smtp.username, cfg.smtp.password, cfg.smtp.sender),
+		genres: validation.NewVocabulary(),
+	}
+	if err := app.loadGenres(); err != nil {
+		app.logger.PrintFatal(err, map[string]string{"msg": "unable to load genres"})
 	}
 	customMetric(db)
 	err := app.server()
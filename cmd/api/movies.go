@@ -1,72 +1,104 @@
 package main
 
 import (
-	"database/sql"
+	"errors"
 	"fmt"
 	"mdb/internal/data"
 	"mdb/internal/validation"
 	"net/http"
 	"strconv"
+	"strings"
 
 	"github.com/gin-gonic/gin"
 )
 
 func (a *application) createMovieHandler(c *gin.Context) {
+	format, ok := a.movieFormat(c)
+	if !ok {
+		return
+	}
 	var movie data.Movie
-	// To do add validation
-	// Like size of body, multiple json value input
-	if err := c.ShouldBindJSON(&movie); err != nil {
-		c.JSON(http.StatusBadRequest, envelope{"errors": validation.Errors(err)})
+	if err := a.bindJSON(c, &movie); err != nil {
+		a.bodyErrorResponse(c, err)
 		return
 	}
+	movie.Genres = a.genres.Normalize(movie.Genres)
 	if err := a.models.Movies.Insert(&movie); err != nil {
-		c.JSON(http.StatusInternalServerError, a.createError(err, "Error while inserting movie"))
+		a.dataErrorResponse(c, err, "Error while inserting movie")
 		return
 	}
 	c.Header("Location", fmt.Sprintf("/v1/movies/%d", movie.ID))
-	c.JSON(http.StatusOK, envelope{"movie": movie})
+	a.renderMovie(c, format, http.StatusOK, envelope{}, nil, &movie)
+}
+
+type showMovieInput struct {
+	Fields []string `form:"fields" binding:"omitempty,csvoneof=id title year runtime genres version external_ids"`
 }
 
 func (a *application) showMovieHandler(c *gin.Context) {
+	format, ok := a.movieFormat(c)
+	if !ok {
+		return
+	}
 	id, err := strconv.ParseInt(c.Param("id"), 10, 64)
 	if err != nil || id < 1 {
-		c.JSON(http.StatusBadRequest, a.createError(err, "Error while converting to int or id is less than 1"))
+		a.errorResponse(c, http.StatusBadRequest, err, "Error while converting to int or id is less than 1")
+		return
+	}
+	var input showMovieInput
+	if err := c.ShouldBindQuery(&input); err != nil {
+		a.failedValidationResponse(c, http.StatusBadRequest, validation.Errors(err))
 		return
 	}
-	movie, err := a.models.Movies.Get(id)
+	input.Fields = splitFields(input.Fields)
+	movie, err := a.models.Movies.Get(id, input.Fields...)
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, &envelope{"error": err.Error()})
+		a.dataErrorResponse(c, err, "Error while reading movie")
 		return
 	}
-	c.JSON(http.StatusOK, &envelope{"movie": movie})
+	collections, err := a.models.Collection.GetAllForMovie(movie.ID)
+	if err != nil {
+		a.dataErrorResponse(c, err, "Error while reading collections of movie")
+		return
+	}
+	a.renderMovie(c, format, http.StatusOK, envelope{"collections": collections}, input.Fields, movie)
 	// c.IndentedJSON(http.StatusOK, &movie) // Will make output prety if used with curl command, but it will expensive than non indented one
 }
 
+// Reason of creating one more struct is that feild type and validation are different than
+// that of movie struct.
+type movieUpdateInput struct {
+	Title   *string       `json:"title" binding:"omitempty,min=1,max=255"`
+	Year    *int32        `json:"year" binding:"omitempty,yearrange"`
+	Runtime *data.Runtime `json:"runtime"`
+	Genres  []string      `json:"genres" binding:"omitempty,genre"`
+}
+
 func (a *application) updateMovieHandler(c *gin.Context) {
+	format, ok := a.movieFormat(c)
+	if !ok {
+		return
+	}
 	id, err := strconv.ParseInt(c.Param("id"), 10, 32)
 	if err != nil {
-		c.JSON(http.StatusBadRequest, a.createError(err, "Id should be a valid integer"))
+		a.errorResponse(c, http.StatusBadRequest, err, "Id should be a valid integer")
 		return
 	}
-	// Reason of creating one more struct is that feild type and validation are different than
-	// that of movie struct.
-	var input struct {
-		Title   *string       `json:"title" binding:"omitempty,min=1,max=255"`
-		Year    *int32        `json:"year" binding:"omitempty,yearrange"`
-		Runtime *data.Runtime `json:"runtime"`
-		Genres  []string      `json:"genres" binding:"unique"`
+	switch c.ContentType() {
+	case mergePatchType, jsonPatchType:
+		a.patchMovieHandler(c, format, id)
+		return
 	}
+	var input movieUpdateInput
 
-	// To do add validation
-	// Like size of body, multiple json value input
-	if err := c.ShouldBindJSON(&input); err != nil {
+	if err := a.bindJSON(c, &input); err != nil {
 		a.logger.PrintError(err, nil)
-		c.JSON(http.StatusBadRequest, envelope{"errors": validation.Errors(err)})
+		a.bodyErrorResponse(c, err)
 		return
 	}
 	dbMovie, err := a.models.Movies.Get(id)
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, &envelope{"error": err.Error()})
+		a.dataErrorResponse(c, err, "Error while reading movie")
 		return
 	}
 	// If the input.Title value is nil then we know that no corresponding "title" key
@@ -80,7 +112,7 @@ func (a *application) updateMovieHandler(c *gin.Context) {
 	}
 
 	if input.Genres != nil {
-		dbMovie.Genres = input.Genres
+		dbMovie.Genres = a.genres.Normalize(input.Genres)
 	}
 	if input.Runtime != nil {
 		dbMovie.Runtime = *input.Runtime
@@ -92,44 +124,287 @@ func (a *application) updateMovieHandler(c *gin.Context) {
 
 	if err := a.models.Movies.Update(dbMovie); err != nil {
 		// https://stackoverflow.com/questions/129329/optimistic-vs-pessimistic-locking/129397#129397
-		// Below is way to stop read condition via optimistic locking
-		if err == sql.ErrNoRows {
-			c.JSON(http.StatusInternalServerError, a.createError(err, "unable to update the record due to an edit conflict, try again"))
-			return
-		}
-		c.JSON(http.StatusInternalServerError, &envelope{"error": err.Error()})
+		// Update returns ErrEditConflict when the version moved on since Get
+		a.dataErrorResponse(c, err, "Error while updating movie")
 		return
 	}
-	c.JSON(http.StatusOK, &envelope{"movie": dbMovie})
+	a.renderMovie(c, format, http.StatusOK, envelope{}, nil, dbMovie)
 }
 
 func (a *application) deleteMovieHandler(c *gin.Context) {
 	id, err := strconv.ParseInt(c.Param("id"), 10, 32)
 	if err != nil {
-		c.JSON(http.StatusBadRequest, a.createError(err, "Id should be a valid integer"))
+		a.errorResponse(c, http.StatusBadRequest, err, "Id should be a valid integer")
 		return
 	}
 	err = a.models.Movies.Delete(id)
 	if err != nil {
-		c.JSON(http.StatusBadRequest, a.createError(err, ""))
+		a.dataErrorResponse(c, err, "Error while deleting movie")
 		return
 	}
 	msg := fmt.Sprintf("Record with ID:%d deleted.", id)
 	c.JSON(http.StatusOK, gin.H{"message": msg})
 }
 
+// Upper bound of the ids accepted by getManyMoviesHandler
+const maxLookupIDs = 100
+
+// getManyMoviesHandler answers GET /v1/movies?ids=1,2,3 with the movies in the
+// order of ids, ids matching no movie are listed under "missing".
+func (a *application) getManyMoviesHandler(c *gin.Context) {
+	format, ok := a.movieFormat(c)
+	if !ok {
+		return
+	}
+	var ids []int64
+	for _, v := range strings.Split(c.Query("ids"), ",") {
+		id, err := strconv.ParseInt(strings.TrimSpace(v), 10, 64)
+		if err != nil || id < 1 {
+			a.failedValidationResponse(c, http.StatusBadRequest, map[string]string{"IDs": "Should be comma separated integers greater than 0"})
+			return
+		}
+		ids = append(ids, id)
+	}
+	if len(ids) > maxLookupIDs {
+		a.failedValidationResponse(c, http.StatusBadRequest, map[string]string{"IDs": fmt.Sprintf("Should be less than %d", maxLookupIDs+1)})
+		return
+	}
+	movies, missing, err := a.models.Movies.GetMany(ids)
+	if err != nil {
+		a.dataErrorResponse(c, err, "Error while reading movies")
+		return
+	}
+	a.renderMovies(c, format, http.StatusOK, envelope{"missing": missing}, nil, movies)
+}
+
 func (a *application) listMoviesHandler(c *gin.Context) {
+	if _, ok := c.GetQuery("ids"); ok {
+		a.getManyMoviesHandler(c)
+		return
+	}
+	format, ok := a.movieFormat(c)
+	if !ok {
+		return
+	}
 	var input data.ListMovie
 	if err := c.ShouldBind(&input); err != nil {
-		c.JSON(http.StatusBadRequest, validation.Errors(err))
+		a.failedValidationResponse(c, http.StatusBadRequest, validation.Errors(err))
 		return
 	}
 	addDefaultValue(&input)
+	input.Genres = a.genres.Normalize(input.Genres)
 	// log.Println(input)
-	mvs, md, err := a.models.Movies.GetAll(input.Title, input.Genres, input.Filters)
+	mvs, md, err := a.models.Movies.GetAll(input)
+	if errors.Is(err, data.ErrInvalidCursor) {
+		a.failedValidationResponse(c, http.StatusBadRequest, map[string]string{"Cursor": err.Error()})
+		return
+	}
+	if err != nil {
+		a.dataErrorResponse(c, err, "Error while reading movies")
+		return
+	}
+	env := envelope{"metadata": md}
+	if len(input.Facets) > 0 {
+		facets, err := a.models.Movies.Facets(input, input.Facets)
+		if err != nil {
+			a.dataErrorResponse(c, err, "Error while counting facets")
+			return
+		}
+		env["facets"] = facets
+	}
+	a.renderMovies(c, format, http.StatusOK, env, input.Fields, mvs)
+}
+
+// searchMoviesHandler is the ranked counterpart of listMoviesHandler, results come
+// best match first with their score and the title highlighted.
+func (a *application) searchMoviesHandler(c *gin.Context) {
+	format, ok := a.movieFormat(c)
+	if !ok {
+		return
+	}
+	var input data.SearchMovie
+	if err := c.ShouldBind(&input); err != nil {
+		a.failedValidationResponse(c, http.StatusBadRequest, validation.Errors(err))
+		return
+	}
+	addDefaultValue(&input.ListMovie)
+	input.Genres = a.genres.Normalize(input.Genres)
+	if input.Language == "" {
+		input.Language = "simple"
+	}
+	results, md, err := a.models.Movies.Search(input)
+	if err != nil {
+		a.dataErrorResponse(c, err, "Error while searching movies")
+		return
+	}
+	a.renderSearchResults(c, format, http.StatusOK, envelope{"metadata": md}, input.Fields, results)
+}
+
+type autocompleteMoviesInput struct {
+	Query string `form:"q" binding:"required,min=1,max=100"`
+	Limit int    `form:"limit" binding:"omitempty,min=1,max=20"`
+}
+
+func (a *application) autocompleteMoviesHandler(c *gin.Context) {
+	var input autocompleteMoviesInput
+	if err := c.ShouldBindQuery(&input); err != nil {
+		a.failedValidationResponse(c, http.StatusBadRequest, validation.Errors(err))
+		return
+	}
+	if input.Limit == 0 {
+		input.Limit = 10
+	}
+	suggestions, err := a.models.Movies.Autocomplete(input.Query, input.Limit)
+	if err != nil {
+		a.dataErrorResponse(c, err, "Error while reading suggestions")
+		return
+	}
+	c.JSON(http.StatusOK, envelope{"suggestions": suggestions})
+}
+
+type movieStatsInput struct {
+	Series bool `form:"series"`
+	Days   int  `form:"days" binding:"omitempty,min=1,max=365"`
+}
+
+func (a *application) movieStatsHandler(c *gin.Context) {
+	var input movieStatsInput
+	if err := c.ShouldBindQuery(&input); err != nil {
+		a.failedValidationResponse(c, http.StatusBadRequest, validation.Errors(err))
+		return
+	}
+	days := 0
+	if input.Series {
+		days = input.Days
+		if days == 0 {
+			days = 30
+		}
+	}
+	stats, err := a.models.Stats.Get(days)
+	if err != nil {
+		a.dataErrorResponse(c, err, "Error while computing statistics")
+		return
+	}
+	c.JSON(http.StatusOK, envelope{"stats": stats})
+}
+
+type externalIDInput struct {
+	Source string `uri:"source" binding:"required,min=1,max=32"`
+	ID     string `uri:"id" binding:"required,min=1,max=255"`
+}
+
+func (a *application) showMovieByExternalHandler(c *gin.Context) {
+	format, ok := a.movieFormat(c)
+	if !ok {
+		return
+	}
+	var ext externalIDInput
+	if err := c.ShouldBindUri(&ext); err != nil {
+		a.failedValidationResponse(c, http.StatusBadRequest, validation.Errors(err))
+		return
+	}
+	movie, err := a.models.Movies.GetByExternalID(strings.ToLower(ext.Source), ext.ID)
+	if err != nil {
+		a.dataErrorResponse(c, err, "Error while reading movie")
+		return
+	}
+	a.renderMovie(c, format, http.StatusOK, envelope{}, nil, movie)
+}
+
+// upsertMovieByExternalHandler creates or replaces the movie known under an id of
+// a partner catalogue, so that syncing a catalogue twice doesn't duplicate movies.
+// It answers 201 when the movie was created and 200 otherwise.
+func (a *application) upsertMovieByExternalHandler(c *gin.Context) {
+	format, ok := a.movieFormat(c)
+	if !ok {
+		return
+	}
+	var ext externalIDInput
+	if err := c.ShouldBindUri(&ext); err != nil {
+		a.failedValidationResponse(c, http.StatusBadRequest, validation.Errors(err))
+		return
+	}
+	var movie data.Movie
+	if err := a.bindJSON(c, &movie); err != nil {
+		a.bodyErrorResponse(c, err)
+		return
+	}
+	movie.Genres = a.genres.Normalize(movie.Genres)
+	status, err := a.models.Movies.UpsertByExternalID(strings.ToLower(ext.Source), ext.ID, &movie)
 	if err != nil {
-		c.JSON(http.StatusBadRequest, a.createError(err, ""))
+		a.dataErrorResponse(c, err, "Error while upserting movie")
+		return
+	}
+	c.Header("Location", fmt.Sprintf("/v1/movies/%d", movie.ID))
+	code := http.StatusOK
+	if status == data.ImportCreated {
+		code = http.StatusCreated
+	}
+	a.renderMovie(c, format, code, envelope{"status": status}, nil, &movie)
+}
+
+type listDuplicateMoviesInput struct {
+	Threshold        float64 `form:"threshold,default=0.6" binding:"gt=0,lte=1"`
+	YearTolerance    int     `form:"year_tolerance,default=1" binding:"min=0,max=10"`
+	RuntimeTolerance int     `form:"runtime_tolerance,default=10" binding:"min=0,max=60"`
+	data.Filters
+}
+
+func (a *application) listDuplicateMoviesHandler(c *gin.Context) {
+	var input listDuplicateMoviesInput
+	if err := c.ShouldBindQuery(&input); err != nil {
+		a.failedValidationResponse(c, http.StatusBadRequest, validation.Errors(err))
+		return
+	}
+	addDefaultFilters(&input.Filters)
+	candidates, md, err := a.models.Movies.FindDuplicates(input.Threshold, input.YearTolerance, input.RuntimeTolerance, input.Filters)
+	if err != nil {
+		a.dataErrorResponse(c, err, "Error while looking for duplicates")
+		return
+	}
+	c.JSON(http.StatusOK, envelope{"metadata": md, "candidates": candidates})
+}
+
+type mergeMovieInput struct {
+	SourceID int64    `json:"source_id" binding:"required,min=1"`
+	Genres   []string `json:"genres" binding:"omitempty,min=1,max=5,genre"`
+}
+
+// mergeMovieHandler folds the movie given as source_id into the movie of the URL,
+// which is the one kept. The kept movie has the genres of both unless genres
+// names them, which is required when both have more than five together.
+func (a *application) mergeMovieHandler(c *gin.Context) {
+	format, ok := a.movieFormat(c)
+	if !ok {
+		return
+	}
+	id, err := strconv.ParseInt(c.Param("id"), 10, 64)
+	if err != nil || id < 1 {
+		a.errorResponse(c, http.StatusBadRequest, fmt.Errorf("invalid id"), "Id should be a valid integer greater than 0")
+		return
+	}
+	var input mergeMovieInput
+	if err := a.bindJSON(c, &input); err != nil {
+		a.bodyErrorResponse(c, err)
+		return
+	}
+	if input.SourceID == id {
+		a.errorResponse(c, http.StatusBadRequest, fmt.Errorf("invalid merge"), "a movie can't be merged into itself")
+		return
+	}
+	var genres []string
+	if len(input.Genres) > 0 {
+		genres = a.genres.Normalize(input.Genres)
+	}
+	movie, err := a.models.Movies.Merge(id, input.SourceID, genres)
+	if err != nil {
+		var tooMany *data.ErrTooManyGenres
+		if errors.As(err, &tooMany) {
+			a.failedValidationResponse(c, http.StatusUnprocessableEntity, map[string]string{"Genres": tooMany.Error() + ", pick them with genres"})
+			return
+		}
+		a.dataErrorResponse(c, err, "Error while merging movies")
 		return
 	}
-	c.JSON(http.StatusOK, envelope{"metadata": md, "movies": mvs})
+	a.renderMovie(c, format, http.StatusOK, envelope{}, nil, movie)
 }
@@ -0,0 +1,45 @@
+package main
+
+import (
+	"io"
+	"mdb/internal/data"
+	"mdb/internal/jsonlog"
+	"mdb/internal/validation"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+)
+
+// TestCreateMovieGenres makes sure JSON bodies can't smuggle several genres, or an
+// unknown one, in a single comma separated element, unlike query strings.
+func TestCreateMovieGenres(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	a := &application{
+		logger: jsonlog.New([]io.Writer{io.Discard}, jsonlog.LevelOff),
+		models: data.Models{Movies: data.MockMovieModel{}},
+		genres: validation.NewVocabulary(),
+	}
+	a.config.body.maxBytes = 1 << 20
+	a.genres.Load([]*data.Genre{{Slug: "drama"}, {Slug: "comedy"}})
+	a.registerValidations()
+
+	tests := map[string]int{
+		`["drama","comedy"]`: http.StatusOK,
+		`["drama,comedy"]`:   http.StatusBadRequest,
+		`["drama,western"]`:  http.StatusBadRequest,
+	}
+	for genres, want := range tests {
+		body := `{"title":"Casablanca","year":1942,"runtime":"102 mins","genres":` + genres + `}`
+		w := httptest.NewRecorder()
+		c, _ := gin.CreateTestContext(w)
+		c.Request = httptest.NewRequest(http.MethodPost, "/v1/movies", strings.NewReader(body))
+		c.Request.Header.Set("Content-Type", "application/json")
+		a.createMovieHandler(c)
+		if w.Code != want {
+			t.Errorf("genres %v: got status %d, want %d: %v", genres, w.Code, want, w.Body.String())
+		}
+	}
+}
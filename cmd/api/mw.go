@@ -1,6 +1,9 @@
 package main
 
 import (
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
 	"expvar"
 	"fmt"
 	"log"
@@ -31,6 +34,22 @@ import (
 // 	}
 // }
 
+// requestID tags every request with the X-Request-ID sent by the client, or a
+// random one, and echoes it in the response so that errors can be traced in logs.
+func (app *application) requestID() gin.HandlerFunc {
+	return func(ctx *gin.Context) {
+		id := ctx.GetHeader("X-Request-ID")
+		if id == "" || len(id) > 64 {
+			b := make([]byte, 16)
+			rand.Read(b)
+			id = hex.EncodeToString(b)
+		}
+		ctx.Set(string(requestIDContextKey), id)
+		ctx.Header("X-Request-ID", id)
+		ctx.Next()
+	}
+}
+
 func (app *application) rateLimiterPerHost() gin.HandlerFunc {
 	if app.config.limiter.enable {
 		type client struct {
@@ -63,7 +82,7 @@ func (app *application) rateLimiterPerHost() gin.HandlerFunc {
 			}
 			if !clients[rip].limiter.Allow() {
 				mu.Unlock()
-				ctx.AbortWithStatusJSON(http.StatusTooManyRequests, gin.H{"msg": "rate limit exceeded"})
+				app.errorResponse(ctx, http.StatusTooManyRequests, nil, "rate limit exceeded")
 				return
 			}
 			mu.Unlock()
@@ -83,17 +102,21 @@ func (app *application) authenticate() gin.HandlerFunc {
 		}
 		headerData := strings.Split(authorizationHeader, " ")
 		if len(headerData) != 2 || headerData[0] != "Bearer" {
-			ctx.AbortWithStatusJSON(http.StatusBadRequest, gin.H{"msg": "Invalid Request"})
+			app.errorResponse(ctx, http.StatusBadRequest, nil, "Authorization header should be: Bearer <token>")
 			return
 		}
 		token := headerData[1]
 		if !data.ValidateTokenPlaintext(token) {
-			ctx.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{"msg": "Invalid Token"})
+			app.errorResponse(ctx, http.StatusUnauthorized, nil, "Invalid Token")
 			return
 		}
 		user, err := app.models.User.GetForToken(data.ScopeAuthentication, token)
 		if err != nil {
-			ctx.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{"msg": "Invalid Token as no user found against it"})
+			if errors.Is(err, data.ErrRecordNotFound) {
+				app.errorResponse(ctx, http.StatusUnauthorized, nil, "Invalid Token as no user found against it")
+				return
+			}
+			app.dataErrorResponse(ctx, err, "Error while reading user of token")
 			return
 		}
 		app.contextSetUser(ctx, user)
@@ -127,7 +150,7 @@ func (app *application) requirePermission(code string) gin.HandlerFunc {
 		user := app.contextGetUser(ctx)
 		perms, err := app.models.Permission.GetAllForUser(user.ID)
 		if err != nil {
-			ctx.AbortWithStatusJSON(http.StatusInternalServerError, gin.H{"msg": "Not able to read permissions"})
+			app.errorResponse(ctx, http.StatusInternalServerError, err, "Not able to read permissions")
 			return
 		}
 		if !perms.Include(code) {
@@ -0,0 +1,788 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"mdb/internal/data"
+	"mdb/internal/validation"
+	"net/http"
+	"reflect"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/gin-gonic/gin/binding"
+)
+
+// apiOperation documents one route of the API. Parameters and bodies are given as
+// zero values of the structs the handler binds, so that the spec is generated from
+// the same form, uri, json and binding tags the requests are validated with.
+type apiOperation struct {
+	id         string // operationId, named after the handler when empty
+	summary    string
+	public     bool   // no bearer token needed
+	permission string // needed on top of an activated user
+	uri        any    // struct with uri tags, path params default to integers for ids
+	query      any    // struct with form tags
+	body       any    // application/json body
+	bodies     map[string]any
+	status     int // 200 when 0
+	response   any // envelope of sample values or any other value
+	formats    []string
+}
+
+// apiOperations has an entry for every route, keyed by method and gin path. The
+// test of this file fails when a route is added or removed without it.
+var apiOperations = map[string]apiOperation{
+	"GET /v1/healthcheck": {
+		summary:  "Report the status of the API",
+		public:   true,
+		response: envelope{"status": "", "system_info": map[string]string{}},
+	},
+	"GET /v1/openapi.json": {
+		id:       "showOpenAPI",
+		summary:  "This OpenAPI document",
+		public:   true,
+		response: map[string]any{},
+	},
+	"GET /debug/vars": {
+		id:       "showMetrics",
+		summary:  "Runtime metrics",
+		public:   true,
+		response: map[string]any{},
+	},
+
+	"GET /v1/movies": {
+		summary:    "List movies, or fetch many movies by id with ids",
+		permission: "movies:read",
+		query: struct {
+			IDs string `form:"ids"`
+			data.ListMovie
+		}{},
+		response: envelope{"metadata": data.Metadata{}, "facets": map[string][]*data.FacetCount{}, "missing": []int64{}, "movies": []*data.Movie{}},
+		formats:  movieFormats,
+	},
+	"GET /v1/movies/search": {
+		summary:    "Search movies, best match first",
+		permission: "movies:read",
+		query:      data.SearchMovie{},
+		response:   envelope{"metadata": data.Metadata{}, "movies": []*data.SearchResult{}},
+		formats:    movieFormats,
+	},
+	"GET /v1/movies/autocomplete": {
+		summary:    "Suggest movies from the start of their title",
+		permission: "movies:read",
+		query:      autocompleteMoviesInput{},
+		response:   envelope{"suggestions": []*data.Suggestion{}},
+	},
+	"GET /v1/movies/stats": {
+		summary:    "Statistics of the catalogue",
+		permission: "movies:read",
+		query:      movieStatsInput{},
+		response:   envelope{"stats": data.Stats{}},
+	},
+	"GET /v1/movies/export": {
+		summary:    "Stream every movie matching the filters",
+		permission: "movies:read",
+		query:      exportMoviesInput{},
+		response:   []*data.Movie{},
+		formats:    []string{binding.MIMEJSON, mimeNDJSON, mimeCSV},
+	},
+	"GET /v1/movies/by-external/:source/:id": {
+		summary:    "Show the movie known under an id of a partner catalogue",
+		permission: "movies:read",
+		uri:        externalIDInput{},
+		response:   envelope{"movie": data.Movie{}},
+		formats:    movieFormats,
+	},
+	"GET /v1/movies/duplicates": {
+		summary:    "List pairs of movies which look like duplicates",
+		permission: "movies:write",
+		query:      listDuplicateMoviesInput{},
+		response:   envelope{"metadata": data.Metadata{}, "candidates": []*data.DuplicateCandidate{}},
+	},
+	"GET /v1/movies/:id": {
+		summary:    "Show a movie along with its collections",
+		permission: "movies:read",
+		query:      showMovieInput{},
+		response:   envelope{"movie": data.Movie{}, "collections": []*data.Collection{}},
+		formats:    movieFormats,
+	},
+	"POST /v1/movies": {
+		summary:    "Create a movie",
+		permission: "movies:write",
+		body:       data.Movie{},
+		response:   envelope{"movie": data.Movie{}},
+		formats:    movieFormats,
+	},
+	"POST /v1/movies/import": {
+		summary:    "Import movies from CSV or NDJSON",
+		permission: "movies:write",
+		query:      importMoviesInput{},
+		bodies:     map[string]any{mimeCSV: "", mimeNDJSON: data.Movie{}},
+		response:   envelope{"dry_run": false, "summary": map[string]int{}, "rows": []*importRow{}},
+	},
+	"POST /v1/movies/batch": {
+		summary:    "Create, update and delete movies in one request",
+		permission: "movies:write",
+		body:       batchMoviesInput{},
+		response:   envelope{"mode": "", "results": []*batchOpResult{}},
+	},
+	"POST /v1/movies/:id/merge": {
+		summary:    "Fold another movie into this one",
+		permission: "movies:write",
+		body:       mergeMovieInput{},
+		response:   envelope{"movie": data.Movie{}},
+		formats:    movieFormats,
+	},
+	"PUT /v1/movies/by-external/:source/:id": {
+		summary:    "Create or replace the movie known under an id of a partner catalogue",
+		permission: "movies:write",
+		uri:        externalIDInput{},
+		body:       data.Movie{},
+		response:   envelope{"movie": data.Movie{}, "status": ""},
+		formats:    movieFormats,
+	},
+	"PUT /v1/movies/:id": {
+		summary:    "Replace a movie",
+		permission: "movies:write",
+		body:       data.Movie{},
+		response:   envelope{"movie": data.Movie{}},
+		formats:    movieFormats,
+	},
+	"PATCH /v1/movies/:id": {
+		summary:    "Update some fields of a movie, with a JSON merge patch or a JSON patch",
+		permission: "movies:write",
+		body:       movieUpdateInput{},
+		bodies:     map[string]any{mergePatchType: movieUpdateInput{}, jsonPatchType: []patchOp{}},
+		response:   envelope{"movie": data.Movie{}},
+		formats:    movieFormats,
+	},
+	"DELETE /v1/movies/:id": {
+		summary:    "Delete a movie",
+		permission: "movies:write",
+		response:   envelope{"message": ""},
+	},
+
+	"GET /v1/genres": {
+		summary:    "List genres with their aliases",
+		permission: "movies:read",
+		response:   envelope{"genres": []*data.Genre{}},
+	},
+	"POST /v1/genres": {
+		summary:    "Create a genre",
+		permission: "genres:write",
+		body:       createGenreInput{},
+		status:     http.StatusCreated,
+		response:   envelope{"genres": []*data.Genre{}},
+	},
+	"PATCH /v1/genres/:slug": {
+		summary:    "Rename a genre",
+		permission: "genres:write",
+		body:       updateGenreInput{},
+		response:   envelope{"genres": []*data.Genre{}},
+	},
+	"DELETE /v1/genres/:slug": {
+		summary:    "Delete a genre no movie uses",
+		permission: "genres:write",
+		response:   envelope{"genres": []*data.Genre{}},
+	},
+	"POST /v1/genres/:slug/aliases": {
+		summary:    "Add an alias to a genre",
+		permission: "genres:write",
+		body:       addGenreAliasInput{},
+		status:     http.StatusCreated,
+		response:   envelope{"genres": []*data.Genre{}},
+	},
+	"DELETE /v1/genres/:slug/aliases/:alias": {
+		summary:    "Remove an alias of a genre",
+		permission: "genres:write",
+		response:   envelope{"genres": []*data.Genre{}},
+	},
+	"POST /v1/genres/:slug/merge": {
+		summary:    "Fold this genre into another one",
+		permission: "genres:write",
+		body:       mergeGenreInput{},
+		response:   envelope{"genres": []*data.Genre{}},
+	},
+
+	"GET /v1/collections": {
+		summary:    "List collections",
+		permission: "movies:read",
+		response:   envelope{"collections": []*data.Collection{}},
+	},
+	"GET /v1/collections/:id": {
+		summary:    "Show a collection along with its movies",
+		permission: "movies:read",
+		response:   envelope{"collection": data.Collection{}, "movies": []*data.Movie{}},
+		formats:    movieFormats,
+	},
+	"POST /v1/collections": {
+		summary:    "Create a collection",
+		permission: "movies:write",
+		body:       createCollectionInput{},
+		status:     http.StatusCreated,
+		response:   envelope{"collection": data.Collection{}},
+	},
+	"PATCH /v1/collections/:id": {
+		summary:    "Update a collection",
+		permission: "movies:write",
+		body:       updateCollectionInput{},
+		response:   envelope{"collection": data.Collection{}},
+	},
+	"DELETE /v1/collections/:id": {
+		summary:    "Delete a collection",
+		permission: "movies:write",
+		response:   envelope{"message": ""},
+	},
+	"POST /v1/collections/:id/movies": {
+		summary:    "Add a movie to a collection",
+		permission: "movies:write",
+		body:       addCollectionMovieInput{},
+		response:   envelope{"collection": data.Collection{}, "movies": []*data.Movie{}},
+		formats:    movieFormats,
+	},
+	"PUT /v1/collections/:id/movies": {
+		summary:    "Reorder the movies of a collection",
+		permission: "movies:write",
+		body:       reorderCollectionMoviesInput{},
+		response:   envelope{"collection": data.Collection{}, "movies": []*data.Movie{}},
+		formats:    movieFormats,
+	},
+	"DELETE /v1/collections/:id/movies/:movie_id": {
+		summary:    "Remove a movie from a collection",
+		permission: "movies:write",
+		response:   envelope{"collection": data.Collection{}, "movies": []*data.Movie{}},
+		formats:    movieFormats,
+	},
+
+	// The :id of a list of the current user is also the kind of a built-in
+	// list, to_watch or watched
+	"GET /v1/users/me/lists": {
+		summary:  "List the lists of the current user",
+		response: envelope{"lists": []*data.List{}},
+	},
+	"POST /v1/users/me/lists": {
+		summary:  "Create a list",
+		body:     createListInput{},
+		status:   http.StatusCreated,
+		response: envelope{"list": data.List{}},
+	},
+	"GET /v1/users/me/lists/:id": {
+		summary:  "Show a list along with a page of its movies",
+		uri:      ownedListParams{},
+		query:    listPageInput{},
+		response: envelope{"list": data.List{}, "metadata": data.Metadata{}, "movies": []*data.Movie{}},
+		formats:  movieFormats,
+	},
+	"PATCH /v1/users/me/lists/:id": {
+		summary:  "Update a list",
+		uri:      ownedListParams{},
+		body:     updateListInput{},
+		response: envelope{"list": data.List{}},
+	},
+	"DELETE /v1/users/me/lists/:id": {
+		summary:  "Delete a list",
+		uri:      ownedListParams{},
+		response: envelope{"message": ""},
+	},
+	"POST /v1/users/me/lists/:id/movies": {
+		summary:  "Add a movie to a list",
+		uri:      ownedListParams{},
+		body:     addListMovieInput{},
+		query:    listPageInput{},
+		response: envelope{"list": data.List{}, "metadata": data.Metadata{}, "movies": []*data.Movie{}},
+		formats:  movieFormats,
+	},
+	"PUT /v1/users/me/lists/:id/movies": {
+		summary:  "Reorder the movies of a list",
+		uri:      ownedListParams{},
+		body:     reorderListMoviesInput{},
+		query:    listPageInput{},
+		response: envelope{"list": data.List{}, "metadata": data.Metadata{}, "movies": []*data.Movie{}},
+		formats:  movieFormats,
+	},
+	"DELETE /v1/users/me/lists/:id/movies/:movie_id": {
+		summary:  "Remove a movie from a list",
+		uri:      ownedListParams{},
+		query:    listPageInput{},
+		response: envelope{"list": data.List{}, "metadata": data.Metadata{}, "movies": []*data.Movie{}},
+		formats:  movieFormats,
+	},
+	"GET /v1/lists/:id": {
+		summary:  "Show a public list along with a page of its movies",
+		query:    data.Filters{},
+		response: envelope{"list": data.List{}, "metadata": data.Metadata{}, "movies": []*data.Movie{}},
+		formats:  movieFormats,
+	},
+
+	"POST /v1/users": {
+		summary:  "Register a user, the activation token is sent by email",
+		public:   true,
+		body:     registerUserInput{},
+		status:   http.StatusAccepted,
+		response: envelope{"msg": "", "user": data.User{}},
+	},
+	"PUT /v1/users/activated": {
+		summary:  "Activate a user with the token sent by email",
+		public:   true,
+		body:     activateUserInput{},
+		response: envelope{"message": "", "user": data.User{}},
+	},
+	"POST /v1/tokens/authentication": {
+		summary:  "Exchange credentials for a bearer token",
+		public:   true,
+		body:     createAuthenticationTokenInput{},
+		response: envelope{"msg": "", "token": data.Token{}},
+	},
+}
+
+// ownedListParams documents the :id of ownedList, which isn't bound from a struct.
+type ownedListParams struct {
+	ID string `uri:"id" binding:"required"`
+}
+
+// openAPIHandler serves the spec of the routes of r. It is built on the first
+// request, once every route is registered.
+func (a *application) openAPIHandler(r *gin.Engine) gin.HandlerFunc {
+	var once sync.Once
+	var spec []byte
+	var err error
+	return func(c *gin.Context) {
+		once.Do(func() {
+			spec, err = json.Marshal(openAPI(r.Routes()))
+		})
+		if err != nil {
+			a.errorResponse(c, http.StatusInternalServerError, err, "")
+			return
+		}
+		c.Data(http.StatusOK, binding.MIMEJSON, spec)
+	}
+}
+
+// openAPI builds the OpenAPI 3 document of routes from apiOperations. Routes
+// without an entry are left out.
+func openAPI(routes gin.RoutesInfo) map[string]any {
+	b := &specBuilder{schemas: map[string]any{}, names: map[reflect.Type]string{}}
+	problemSchema := b.schema(reflect.TypeOf(problem{}), nil)
+	paths := map[string]map[string]any{}
+	for _, route := range routes {
+		op, ok := apiOperations[route.Method+" "+route.Path]
+		if !ok {
+			continue
+		}
+		path, params := specPath(route.Path)
+		if paths[path] == nil {
+			paths[path] = map[string]any{}
+		}
+		paths[path][strings.ToLower(route.Method)] = b.operation(route, op, params, problemSchema)
+	}
+	v := version
+	if v == "" {
+		v = "dev"
+	}
+	return map[string]any{
+		"openapi": "3.0.3",
+		"info": map[string]any{
+			"title":   "mdb",
+			"version": v,
+		},
+		"paths": paths,
+		"components": map[string]any{
+			"schemas": b.schemas,
+			"securitySchemes": map[string]any{
+				"bearerAuth": map[string]any{"type": "http", "scheme": "bearer"},
+			},
+		},
+		"security": []any{map[string]any{"bearerAuth": []string{}}},
+	}
+}
+
+// specPath turns the gin path /v1/movies/:id into /v1/movies/{id} and returns the
+// names of its params.
+func specPath(path string) (string, []string) {
+	var params []string
+	parts := strings.Split(path, "/")
+	for i, p := range parts {
+		if strings.HasPrefix(p, ":") || strings.HasPrefix(p, "*") {
+			params = append(params, p[1:])
+			parts[i] = "{" + p[1:] + "}"
+		}
+	}
+	return strings.Join(parts, "/"), params
+}
+
+// operationID turns the name of the handler, e.g.
+// main.(*application).listMoviesHandler-fm, into listMovies.
+func operationID(route gin.RouteInfo, op apiOperation) string {
+	if op.id != "" {
+		return op.id
+	}
+	name := route.Handler[strings.LastIndex(route.Handler, ".")+1:]
+	return strings.TrimSuffix(strings.TrimSuffix(name, "-fm"), "Handler")
+}
+
+type specBuilder struct {
+	schemas map[string]any
+	names   map[reflect.Type]string
+}
+
+func (b *specBuilder) operation(route gin.RouteInfo, op apiOperation, pathParams []string, problemSchema map[string]any) map[string]any {
+	out := map[string]any{
+		"operationId": operationID(route, op),
+		"summary":     op.summary,
+	}
+	if tag := strings.Split(strings.TrimPrefix(route.Path, "/v1"), "/"); len(tag) > 1 {
+		out["tags"] = []string{tag[1]}
+	}
+	if op.public {
+		out["security"] = []any{}
+	} else if op.permission != "" {
+		out["description"] = fmt.Sprintf("Needs an activated user with the %v permission.", op.permission)
+	} else {
+		out["description"] = "Needs an activated user."
+	}
+
+	var params []any
+	uri := map[string]structField{}
+	if op.uri != nil {
+		for _, f := range structFields(reflect.TypeOf(op.uri), "uri") {
+			uri[f.name] = f
+		}
+	}
+	for _, name := range pathParams {
+		s := map[string]any{"type": "string"}
+		if f, ok := uri[name]; ok {
+			s = b.schema(f.typ, f.tags)
+		} else if name == "id" || strings.HasSuffix(name, "_id") {
+			s = map[string]any{"type": "integer", "format": "int64", "minimum": 1}
+		}
+		params = append(params, map[string]any{"name": name, "in": "path", "required": true, "schema": s})
+	}
+	if op.query != nil {
+		for _, f := range structFields(reflect.TypeOf(op.query), "form") {
+			var s map[string]any
+			if f.typ == runtimeType {
+				// Query params are plain minutes, unlike the "102 mins" of JSON
+				s = map[string]any{"type": "integer", "format": "int32", "description": "Minutes"}
+				applyBinding(s, f.tags)
+			} else {
+				s = b.schema(f.typ, f.tags)
+			}
+			if f.def != "" {
+				s["default"] = defaultValue(s, f.def)
+			}
+			param := map[string]any{"name": f.name, "in": "query", "schema": s}
+			if hasTag(f.tags, "required") {
+				param["required"] = true
+			}
+			if hasTag(f.tags, "csvoneof") || hasTag(f.tags, "csvgenre") {
+				param["explode"] = false
+			}
+			params = append(params, param)
+		}
+	}
+	if len(params) > 0 {
+		out["parameters"] = params
+	}
+
+	if op.body != nil || len(op.bodies) > 0 {
+		content := map[string]any{}
+		if op.body != nil {
+			content[binding.MIMEJSON] = map[string]any{"schema": b.schema(reflect.TypeOf(op.body), nil)}
+		}
+		for typ, body := range op.bodies {
+			content[typ] = map[string]any{"schema": b.schema(reflect.TypeOf(body), nil)}
+		}
+		out["requestBody"] = map[string]any{"required": true, "content": content}
+	}
+
+	status := op.status
+	if status == 0 {
+		status = http.StatusOK
+	}
+	content := map[string]any{}
+	formats := op.formats
+	if len(formats) == 0 {
+		formats = []string{binding.MIMEJSON}
+	}
+	for _, format := range formats {
+		var s map[string]any
+		switch format {
+		case mimeNDJSON:
+			// One movie per line
+			s = b.schema(reflect.TypeOf(data.Movie{}), nil)
+		case mimeCSV:
+			s = map[string]any{"type": "string"}
+		default:
+			s = b.response(op.response)
+		}
+		content[format] = map[string]any{"schema": s}
+	}
+	out["responses"] = map[string]any{
+		strconv.Itoa(status): map[string]any{"description": http.StatusText(status), "content": content},
+		"default": map[string]any{
+			"description": "Error",
+			"content":     map[string]any{"application/problem+json": map[string]any{"schema": problemSchema}},
+		},
+	}
+	return out
+}
+
+// response is the schema of the sample value of an operation, an envelope is an
+// object with a property per key.
+func (b *specBuilder) response(v any) map[string]any {
+	env, ok := v.(envelope)
+	if !ok {
+		return b.schema(reflect.TypeOf(v), nil)
+	}
+	props := map[string]any{}
+	for k, v := range env {
+		props[k] = b.schema(reflect.TypeOf(v), nil)
+	}
+	return map[string]any{"type": "object", "properties": props}
+}
+
+var (
+	runtimeType    = reflect.TypeOf(data.Runtime(0))
+	timeType       = reflect.TypeOf(time.Time{})
+	rawMessageType = reflect.TypeOf(json.RawMessage{})
+)
+
+// schema is the JSON schema of t, constrained by the binding tags of the field
+// it comes from. Named structs are added to the components and referenced.
+func (b *specBuilder) schema(t reflect.Type, tags []string) map[string]any {
+	for t.Kind() == reflect.Pointer {
+		t = t.Elem()
+	}
+	// Tags after dive apply to the items of a slice
+	var itemTags []string
+	for i, tag := range tags {
+		if tag == "dive" {
+			tags, itemTags = tags[:i], tags[i+1:]
+			break
+		}
+	}
+	var s map[string]any
+	switch {
+	case t == runtimeType:
+		s = map[string]any{"type": "string", "format": "runtime", "pattern": `^\d+ mins$`, "example": "102 mins"}
+	case t == timeType:
+		s = map[string]any{"type": "string", "format": "date-time"}
+	case t == rawMessageType:
+		s = map[string]any{}
+	default:
+		switch t.Kind() {
+		case reflect.Bool:
+			s = map[string]any{"type": "boolean"}
+		case reflect.Int, reflect.Int64, reflect.Uint, reflect.Uint64:
+			s = map[string]any{"type": "integer", "format": "int64"}
+		case reflect.Int8, reflect.Int16, reflect.Int32, reflect.Uint8, reflect.Uint16, reflect.Uint32:
+			s = map[string]any{"type": "integer", "format": "int32"}
+		case reflect.Float32, reflect.Float64:
+			s = map[string]any{"type": "number"}
+		case reflect.String:
+			s = map[string]any{"type": "string"}
+		case reflect.Slice, reflect.Array:
+			if t.Elem().Kind() == reflect.Uint8 {
+				s = map[string]any{"type": "string", "format": "byte"}
+				break
+			}
+			s = map[string]any{"type": "array", "items": b.schema(t.Elem(), itemTags)}
+		case reflect.Map:
+			s = map[string]any{"type": "object", "additionalProperties": b.schema(t.Elem(), itemTags)}
+		case reflect.Struct:
+			if t.Name() == "" {
+				s = b.object(t)
+				break
+			}
+			return map[string]any{"$ref": "#/components/schemas/" + b.component(t)}
+		default:
+			s = map[string]any{}
+		}
+	}
+	applyBinding(s, tags)
+	return s
+}
+
+// component adds the named struct t to the components, once, and returns its
+// name there.
+func (b *specBuilder) component(t reflect.Type) string {
+	if name, ok := b.names[t]; ok {
+		return name
+	}
+	name := strings.ToUpper(t.Name()[:1]) + t.Name()[1:]
+	if _, taken := b.schemas[name]; taken {
+		pkg := t.PkgPath()[strings.LastIndex(t.PkgPath(), "/")+1:]
+		name = strings.ToUpper(pkg[:1]) + pkg[1:] + name
+	}
+	b.names[t] = name
+	b.schemas[name] = map[string]any{} // placeholder for recursive types
+	b.schemas[name] = b.object(t)
+	return name
+}
+
+func (b *specBuilder) object(t reflect.Type) map[string]any {
+	props := map[string]any{}
+	var required []string
+	for _, f := range structFields(t, "json") {
+		props[f.name] = b.schema(f.typ, f.tags)
+		if hasTag(f.tags, "required") {
+			required = append(required, f.name)
+		}
+	}
+	s := map[string]any{"type": "object", "properties": props}
+	if len(required) > 0 {
+		s["required"] = required
+	}
+	return s
+}
+
+type structField struct {
+	name string
+	typ  reflect.Type
+	tags []string // binding tags
+	def  string   // default of a form field
+}
+
+// structFields lists the fields of t under their name for the key tag ("json",
+// "form" or "uri"). Embedded structs are flattened the way encoding/json and gin
+// do and the field of gtefield is renamed after the tag.
+func structFields(t reflect.Type, key string) []structField {
+	for t.Kind() == reflect.Pointer {
+		t = t.Elem()
+	}
+	var out []structField
+	renamed := map[string]string{}
+	for i := 0; i < t.NumField(); i++ {
+		f := t.Field(i)
+		name, opts, _ := strings.Cut(f.Tag.Get(key), ",")
+		if name == "-" {
+			continue
+		}
+		ft := f.Type
+		for ft.Kind() == reflect.Pointer {
+			ft = ft.Elem()
+		}
+		if f.Anonymous && name == "" && ft.Kind() == reflect.Struct {
+			out = append(out, structFields(ft, key)...)
+			continue
+		}
+		if !f.IsExported() {
+			continue
+		}
+		if name == "" {
+			name = f.Name
+		}
+		sf := structField{name: name, typ: f.Type}
+		if tag := f.Tag.Get("binding"); tag != "" {
+			sf.tags = strings.Split(tag, ",")
+		}
+		for _, o := range strings.Split(opts, ",") {
+			if strings.HasPrefix(o, "default=") {
+				sf.def = strings.TrimPrefix(o, "default=")
+			}
+		}
+		renamed[f.Name] = name
+		out = append(out, sf)
+	}
+	for _, sf := range out {
+		for i, tag := range sf.tags {
+			name, field, _ := strings.Cut(tag, "=")
+			if name == "gtefield" && renamed[field] != "" {
+				sf.tags[i] = "gtefield=" + renamed[field]
+			}
+		}
+	}
+	return out
+}
+
+func hasTag(tags []string, name string) bool {
+	for _, tag := range tags {
+		if tag == name || strings.HasPrefix(tag, name+"=") {
+			return true
+		}
+	}
+	return false
+}
+
+// applyBinding adds the constraints of the binding tags to s, how min and max
+// apply depends on the type of s.
+func applyBinding(s map[string]any, tags []string) {
+	typ, _ := s["type"].(string)
+	bound := func(kw string, param string) {
+		switch typ {
+		case "string":
+			s[kw+"Length"] = number(param)
+		case "array":
+			s[kw+"Items"] = number(param)
+		case "integer", "number":
+			s[kw+"imum"] = number(param)
+		}
+	}
+	for _, tag := range tags {
+		name, param, _ := strings.Cut(tag, "=")
+		switch name {
+		case "min", "gte":
+			bound("min", param)
+		case "max", "lte":
+			bound("max", param)
+		case "len":
+			bound("min", param)
+			bound("max", param)
+		case "gt":
+			bound("min", param)
+			s["exclusiveMinimum"] = true
+		case "lt":
+			bound("max", param)
+			s["exclusiveMaximum"] = true
+		case "oneof":
+			var enum []any
+			for _, v := range strings.Fields(param) {
+				enum = append(enum, defaultValue(s, v))
+			}
+			s["enum"] = enum
+		case "csvoneof":
+			if items, ok := s["items"].(map[string]any); ok {
+				items["enum"] = strings.Fields(param)
+			}
+		case "unique":
+			s["uniqueItems"] = true
+		case "genre", "csvgenre":
+			s["uniqueItems"] = true
+			s["description"] = "Slugs or aliases of known genres, see /v1/genres"
+		case "email":
+			s["format"] = "email"
+		case "yearrange":
+			s["minimum"] = validation.MinYear + 1
+			s["maximum"] = time.Now().Year()
+		case "runtimerange":
+			s["minimum"] = data.MinRuntime
+			s["maximum"] = data.MaxRuntime
+		case "gtefield":
+			s["description"] = "Greater than or equal to " + param
+		}
+	}
+}
+
+// number parses the param of a binding tag, which are all integers or floats.
+func number(param string) any {
+	if n, err := strconv.ParseInt(param, 10, 64); err == nil {
+		return n
+	}
+	f, _ := strconv.ParseFloat(param, 64)
+	return f
+}
+
+// defaultValue types v after the schema s it belongs to.
+func defaultValue(s map[string]any, v string) any {
+	switch s["type"] {
+	case "integer", "number":
+		return number(v)
+	case "boolean":
+		return v == "true"
+	}
+	return v
+}
@@ -0,0 +1,47 @@
+package main
+
+import (
+	"encoding/json"
+	"io"
+	"mdb/internal/jsonlog"
+	"strings"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+)
+
+// TestOpenAPIMatchesRoutes fails when a route is added or removed without its
+// entry in apiOperations, so that the spec can't drift from the router.
+func TestOpenAPIMatchesRoutes(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	a := &application{logger: jsonlog.New([]io.Writer{io.Discard}, jsonlog.LevelOff)}
+	routes := a.routes().Routes()
+
+	routed := map[string]bool{}
+	for _, route := range routes {
+		key := route.Method + " " + route.Path
+		routed[key] = true
+		if _, ok := apiOperations[key]; !ok {
+			t.Errorf("route %v is missing from apiOperations", key)
+		}
+	}
+	for key := range apiOperations {
+		if !routed[key] {
+			t.Errorf("apiOperations documents %v which is not routed", key)
+		}
+	}
+
+	spec := openAPI(routes)
+	js, err := json.Marshal(spec)
+	if err != nil {
+		t.Fatalf("unable to encode the spec: %v", err)
+	}
+	// Every reference has to resolve to a component
+	schemas := spec["components"].(map[string]any)["schemas"].(map[string]any)
+	for _, ref := range strings.Split(string(js), `"$ref":"#/components/schemas/`)[1:] {
+		name := ref[:strings.Index(ref, `"`)]
+		if _, ok := schemas[name]; !ok {
+			t.Errorf("schema %v is referenced but not defined", name)
+		}
+	}
+}
@@ -0,0 +1,336 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"mdb/internal/data"
+	"mdb/internal/validation"
+	"net/http"
+	"reflect"
+	"strconv"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"github.com/gin-gonic/gin/binding"
+)
+
+const (
+	mergePatchType = "application/merge-patch+json"
+	jsonPatchType  = "application/json-patch+json"
+)
+
+var errPatchTest = errors.New("test operation failed")
+
+// patchOp is one operation of a RFC 6902 JSON Patch. Only add, remove, replace
+// and test are supported.
+type patchOp struct {
+	Op    string           `json:"op"`
+	Path  string           `json:"path"`
+	Value *json.RawMessage `json:"value"`
+}
+
+// movieDocument is the part of a movie a patch applies to, the version is part of
+// it so that a patch can carry the version it was written against.
+func movieDocument(m *data.Movie) (map[string]any, error) {
+	js, err := json.Marshal(struct {
+		Title   string       `json:"title"`
+		Year    int32        `json:"year"`
+		Runtime data.Runtime `json:"runtime"`
+		Genres  []string     `json:"genres"`
+		Version int32        `json:"version"`
+	}{m.Title, m.Year, m.Runtime, m.Genres, m.Version})
+	if err != nil {
+		return nil, err
+	}
+	var doc map[string]any
+	return doc, decodeJSON(js, &doc)
+}
+
+// decodeJSON decodes a single JSON value, numbers are kept as json.Number so
+// that they compare and encode back exactly.
+func decodeJSON(js []byte, v any) error {
+	dec := json.NewDecoder(bytes.NewReader(js))
+	dec.UseNumber()
+	if err := dec.Decode(v); err != nil {
+		return decodeError(err)
+	}
+	if err := dec.Decode(&struct{}{}); err != io.EOF {
+		return &bodyError{http.StatusBadRequest, "body must only contain a single JSON value"}
+	}
+	return nil
+}
+
+// mergePatch applies a RFC 7396 merge patch: null removes a member, objects are
+// merged recursively and any other value replaces the target.
+func mergePatch(target any, patch any) any {
+	p, ok := patch.(map[string]any)
+	if !ok {
+		return patch
+	}
+	t, ok := target.(map[string]any)
+	if !ok {
+		t = map[string]any{}
+	}
+	for k, v := range p {
+		if v == nil {
+			delete(t, k)
+			continue
+		}
+		t[k] = mergePatch(t[k], v)
+	}
+	return t
+}
+
+// jsonPatch applies the operations of a RFC 6902 patch to doc, in order. The
+// first failing operation stops the patch.
+func jsonPatch(doc any, ops []patchOp) (any, error) {
+	for i, op := range ops {
+		var value any
+		if op.Value != nil {
+			if err := decodeJSON(*op.Value, &value); err != nil {
+				return nil, err
+			}
+		}
+		tokens, err := pointerTokens(op.Path)
+		if err != nil {
+			return nil, fmt.Errorf("operation %d: %w", i, err)
+		}
+		switch op.Op {
+		case "add", "replace":
+			if op.Value == nil {
+				return nil, fmt.Errorf("operation %d: value is needed", i)
+			}
+			doc, err = patchApply(doc, tokens, op.Op, value)
+		case "remove":
+			doc, err = patchApply(doc, tokens, op.Op, nil)
+		case "test":
+			var current any
+			current, err = pointerGet(doc, tokens)
+			if err == nil && !reflect.DeepEqual(current, value) {
+				err = fmt.Errorf("%w at %v", errPatchTest, op.Path)
+			}
+		default:
+			err = fmt.Errorf("unsupported op %q, use add, remove, replace or test", op.Op)
+		}
+		if err != nil {
+			return nil, fmt.Errorf("operation %d: %w", i, err)
+		}
+	}
+	return doc, nil
+}
+
+func pointerTokens(path string) ([]string, error) {
+	if path == "" {
+		return nil, nil
+	}
+	if !strings.HasPrefix(path, "/") {
+		return nil, fmt.Errorf("path %q should start with /", path)
+	}
+	tokens := strings.Split(path[1:], "/")
+	for i, t := range tokens {
+		tokens[i] = strings.NewReplacer("~1", "/", "~0", "~").Replace(t)
+	}
+	return tokens, nil
+}
+
+func pointerGet(doc any, tokens []string) (any, error) {
+	for _, t := range tokens {
+		switch v := doc.(type) {
+		case map[string]any:
+			next, ok := v[t]
+			if !ok {
+				return nil, fmt.Errorf("no member %q", t)
+			}
+			doc = next
+		case []any:
+			i, err := strconv.Atoi(t)
+			if err != nil || i < 0 || i >= len(v) {
+				return nil, fmt.Errorf("no index %q", t)
+			}
+			doc = v[i]
+		default:
+			return nil, fmt.Errorf("no member %q", t)
+		}
+	}
+	return doc, nil
+}
+
+// patchApply runs add, replace or remove at tokens and returns the updated doc.
+func patchApply(doc any, tokens []string, op string, value any) (any, error) {
+	if len(tokens) == 0 {
+		if op == "remove" {
+			return nil, fmt.Errorf("the whole document can't be removed")
+		}
+		return value, nil
+	}
+	t := tokens[0]
+	switch v := doc.(type) {
+	case map[string]any:
+		if len(tokens) > 1 {
+			next, ok := v[t]
+			if !ok {
+				return nil, fmt.Errorf("no member %q", t)
+			}
+			updated, err := patchApply(next, tokens[1:], op, value)
+			if err != nil {
+				return nil, err
+			}
+			v[t] = updated
+			return v, nil
+		}
+		if _, ok := v[t]; !ok && op != "add" {
+			return nil, fmt.Errorf("no member %q", t)
+		}
+		if op == "remove" {
+			delete(v, t)
+		} else {
+			v[t] = value
+		}
+		return v, nil
+	case []any:
+		if t == "-" && op == "add" && len(tokens) == 1 {
+			return append(v, value), nil
+		}
+		i, err := strconv.Atoi(t)
+		max := len(v) - 1
+		if op == "add" && len(tokens) == 1 {
+			max = len(v)
+		}
+		if err != nil || i < 0 || i > max {
+			return nil, fmt.Errorf("no index %q", t)
+		}
+		if len(tokens) > 1 {
+			updated, err := patchApply(v[i], tokens[1:], op, value)
+			if err != nil {
+				return nil, err
+			}
+			v[i] = updated
+			return v, nil
+		}
+		switch op {
+		case "add":
+			v = append(v[:i], append([]any{value}, v[i:]...)...)
+		case "remove":
+			v = append(v[:i], v[i+1:]...)
+		default:
+			v[i] = value
+		}
+		return v, nil
+	default:
+		return nil, fmt.Errorf("no member %q", t)
+	}
+}
+
+// patchMovieHandler is the PATCH /v1/movies/:id counterpart of updateMovieHandler
+// for merge patches and JSON patches. The patch applies to the current movie and
+// the result has to pass the same validation as a created movie. The version of
+// the patched document must match the stored one, a patch can't remove it.
+func (a *application) patchMovieHandler(c *gin.Context, format string, id int64) {
+	body, err := a.readBody(c)
+	if err != nil {
+		a.bodyErrorResponse(c, err)
+		return
+	}
+	dbMovie, err := a.models.Movies.Get(id)
+	if err != nil {
+		a.dataErrorResponse(c, err, "Error while reading movie")
+		return
+	}
+	doc, err := movieDocument(dbMovie)
+	if err != nil {
+		a.errorResponse(c, http.StatusInternalServerError, err, "")
+		return
+	}
+	var patched any
+	if c.ContentType() == mergePatchType {
+		var patch any
+		if err := decodeJSON(body, &patch); err != nil {
+			a.bodyErrorResponse(c, err)
+			return
+		}
+		patched = mergePatch(doc, patch)
+	} else {
+		var ops []patchOp
+		if err := decodeStrict(bytes.NewReader(body), &ops); err != nil {
+			a.bodyErrorResponse(c, err)
+			return
+		}
+		patched, err = jsonPatch(doc, ops)
+		if errors.Is(err, errPatchTest) {
+			a.errorResponse(c, http.StatusConflict, err, "")
+			return
+		}
+		if err != nil {
+			a.errorResponse(c, http.StatusUnprocessableEntity, err, "")
+			return
+		}
+	}
+	js, err := json.Marshal(patched)
+	if err != nil {
+		a.errorResponse(c, http.StatusInternalServerError, err, "")
+		return
+	}
+	// Members the patch added which are not part of a movie are rejected
+	var movie data.Movie
+	if err := decodeStrict(bytes.NewReader(js), &movie); err != nil {
+		a.failedValidationResponse(c, http.StatusUnprocessableEntity, validation.Errors(err))
+		return
+	}
+	if err := binding.Validator.ValidateStruct(&movie); err != nil {
+		a.failedValidationResponse(c, http.StatusUnprocessableEntity, validation.Errors(err))
+		return
+	}
+	if movie.Version == 0 {
+		a.failedValidationResponse(c, http.StatusUnprocessableEntity, map[string]string{"Version": "Can't be removed by a patch"})
+		return
+	}
+	a.replaceMovie(c, format, dbMovie, &movie)
+}
+
+// replaceMovieHandler replaces every field of a movie, the body follows the rules
+// of createMovieHandler along with the version it replaces, which must match the
+// stored one.
+func (a *application) replaceMovieHandler(c *gin.Context) {
+	format, ok := a.movieFormat(c)
+	if !ok {
+		return
+	}
+	id, err := strconv.ParseInt(c.Param("id"), 10, 64)
+	if err != nil || id < 1 {
+		a.errorResponse(c, http.StatusBadRequest, fmt.Errorf("invalid id %q", c.Param("id")), "Id should be a valid integer")
+		return
+	}
+	var movie data.Movie
+	if err := a.bindJSON(c, &movie); err != nil {
+		a.bodyErrorResponse(c, err)
+		return
+	}
+	if movie.Version == 0 {
+		a.failedValidationResponse(c, http.StatusBadRequest, map[string]string{"Version": "Is needed"})
+		return
+	}
+	dbMovie, err := a.models.Movies.Get(id)
+	if err != nil {
+		a.dataErrorResponse(c, err, "Error while reading movie")
+		return
+	}
+	a.replaceMovie(c, format, dbMovie, &movie)
+}
+
+// replaceMovie stores movie in place of dbMovie, keeping its id and external ids.
+// movie.Version is the version the caller read.
+func (a *application) replaceMovie(c *gin.Context, format string, dbMovie, movie *data.Movie) {
+	movie.ID = dbMovie.ID
+	movie.CreatedAt = dbMovie.CreatedAt
+	movie.ExternalIDs = dbMovie.ExternalIDs
+	movie.Genres = a.genres.Normalize(movie.Genres)
+	if err := a.models.Movies.Update(movie); err != nil {
+		// Update returns ErrEditConflict when the version moved on
+		a.dataErrorResponse(c, err, "Error while updating movie")
+		return
+	}
+	a.renderMovie(c, format, http.StatusOK, envelope{}, nil, movie)
+}
@@ -0,0 +1,264 @@
+package main
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"mdb/internal/data"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"github.com/gin-gonic/gin/binding"
+	"github.com/gin-gonic/gin/render"
+)
+
+const (
+	mimeNDJSON = "application/x-ndjson"
+	mimeCSV    = "text/csv"
+)
+
+// Formats of the movie endpoints, the first one is used when the client sends no
+// Accept header.
+var movieFormats = []string{binding.MIMEJSON, mimeNDJSON, mimeCSV, binding.MIMEMSGPACK2, binding.MIMEMSGPACK}
+
+// Columns of CSV responses when the client doesn't pick fields
+var csvMovieColumns = []string{"id", "title", "year", "runtime", "genres", "version"}
+
+// negotiate returns the offer matching the Accept header best, honouring q values
+// and wildcards. It returns "" when nothing offered is acceptable.
+func negotiate(accept string, offered []string) string {
+	if strings.TrimSpace(accept) == "" {
+		return offered[0]
+	}
+	type mediaRange struct {
+		typ string
+		q   float64
+	}
+	var ranges []mediaRange
+	for _, part := range strings.Split(accept, ",") {
+		params := strings.Split(part, ";")
+		r := mediaRange{typ: strings.ToLower(strings.TrimSpace(params[0])), q: 1}
+		for _, p := range params[1:] {
+			if k, v, ok := strings.Cut(strings.TrimSpace(p), "="); ok && k == "q" {
+				if q, err := strconv.ParseFloat(v, 64); err == nil {
+					r.q = q
+				}
+			}
+		}
+		if r.typ != "" && r.q > 0 {
+			ranges = append(ranges, r)
+		}
+	}
+	sort.SliceStable(ranges, func(i, j int) bool { return ranges[i].q > ranges[j].q })
+	for _, r := range ranges {
+		for _, offer := range offered {
+			typ, _, _ := strings.Cut(offer, "/")
+			if r.typ == offer || r.typ == "*/*" || r.typ == typ+"/*" {
+				return offer
+			}
+		}
+	}
+	return ""
+}
+
+// movieValues is a movie with its runtime in integer minutes, for the formats
+// which have numbers of their own. Only fields are kept unless it is empty.
+func movieValues(m *data.Movie, fields []string) map[string]any {
+	all := map[string]any{
+		"id":      m.ID,
+		"title":   m.Title,
+		"year":    m.Year,
+		"runtime": int32(m.Runtime),
+		"genres":  m.Genres,
+		"version": m.Version,
+	}
+	if len(m.ExternalIDs) > 0 {
+		all["external_ids"] = map[string]string(m.ExternalIDs)
+	}
+	if len(fields) == 0 {
+		return all
+	}
+	kept := map[string]any{}
+	for _, f := range fields {
+		if v, ok := all[f]; ok {
+			kept[f] = v
+		}
+	}
+	return kept
+}
+
+// csvValue renders one column of a movie, the runtime in integer minutes and
+// lists comma separated within their field.
+func csvValue(m *data.Movie, col string) string {
+	switch col {
+	case "id":
+		return strconv.FormatInt(m.ID, 10)
+	case "title":
+		return m.Title
+	case "year":
+		return strconv.Itoa(int(m.Year))
+	case "runtime":
+		return strconv.Itoa(int(m.Runtime))
+	case "genres":
+		return strings.Join(m.Genres, ",")
+	case "version":
+		return strconv.Itoa(int(m.Version))
+	case "external_ids":
+		ids := make([]string, 0, len(m.ExternalIDs))
+		for source, id := range m.ExternalIDs {
+			ids = append(ids, source+":"+id)
+		}
+		sort.Strings(ids)
+		return strings.Join(ids, ",")
+	default:
+		return ""
+	}
+}
+
+// movieFormat negotiates the format of the movies of the response from the Accept
+// header. When none is acceptable it answers 406 and returns false, handlers call
+// it before reaching the models so that such a request changes nothing.
+func (a *application) movieFormat(c *gin.Context) (string, bool) {
+	format := negotiate(c.GetHeader("Accept"), movieFormats)
+	if format == "" {
+		a.errorResponse(c, http.StatusNotAcceptable, nil,
+			fmt.Sprintf("movies can be rendered as %v", strings.Join(movieFormats, ", ")))
+		return "", false
+	}
+	return format, true
+}
+
+// movieExtras are values rendered along with each movie, such as the score of a
+// search result. cols is their order in CSV, values[i] goes with the i-th movie.
+type movieExtras struct {
+	cols   []string
+	values []map[string]any
+}
+
+// renderMovie sends env with movie under "movie" in format, as returned by
+// movieFormat. See renderMovies.
+func (a *application) renderMovie(c *gin.Context, format string, status int, env envelope, fields []string, movie *data.Movie) {
+	a.renderMovieFormats(c, format, status, env, "movie", fields, []*data.Movie{movie}, nil)
+}
+
+// renderMovies sends env with movies under "movies" in format, as returned by
+// movieFormat. JSON and MessagePack carry the whole envelope while NDJSON and CSV
+// only have room for the movies, one per line.
+func (a *application) renderMovies(c *gin.Context, format string, status int, env envelope, fields []string, movies []*data.Movie) {
+	a.renderMovieFormats(c, format, status, env, "movies", fields, movies, nil)
+}
+
+// renderSearchResults is renderMovies for search results, their score and
+// highlight come along with the fields of each movie.
+func (a *application) renderSearchResults(c *gin.Context, format string, status int, env envelope, fields []string, results []*data.SearchResult) {
+	movies := make([]*data.Movie, len(results))
+	extras := &movieExtras{cols: []string{"score", "highlight"}, values: make([]map[string]any, len(results))}
+	for i, r := range results {
+		movies[i] = &r.Movie
+		extras.values[i] = map[string]any{"score": r.Score, "highlight": r.Highlight}
+	}
+	a.renderMovieFormats(c, format, status, env, "movies", fields, movies, extras)
+}
+
+func (a *application) renderMovieFormats(c *gin.Context, format string, status int, env envelope, key string, fields []string, movies []*data.Movie, extras *movieExtras) {
+	switch format {
+	case binding.MIMEJSON, mimeNDJSON:
+		out, err := onlyFields(fields, movies...)
+		if err == nil && extras != nil {
+			for i := range out {
+				if out[i], err = withExtras(out[i], extras.values[i]); err != nil {
+					break
+				}
+			}
+		}
+		if err != nil {
+			a.errorResponse(c, http.StatusInternalServerError, err, "")
+			return
+		}
+		if format == mimeNDJSON {
+			c.Header("Content-Type", mimeNDJSON)
+			c.Status(status)
+			enc := json.NewEncoder(c.Writer)
+			for _, m := range out {
+				if err := enc.Encode(m); err != nil {
+					a.logger.PrintError(err, map[string]string{"msg": "unable to write movies"})
+					return
+				}
+			}
+			return
+		}
+		if key == "movie" {
+			env[key] = out[0]
+		} else {
+			env[key] = out
+		}
+		c.JSON(status, env)
+	case binding.MIMEMSGPACK2, binding.MIMEMSGPACK:
+		out := make([]map[string]any, len(movies))
+		for i, m := range movies {
+			out[i] = movieValues(m, fields)
+			if extras != nil {
+				for k, v := range extras.values[i] {
+					out[i][k] = v
+				}
+			}
+		}
+		if key == "movie" {
+			env[key] = out[0]
+		} else {
+			env[key] = out
+		}
+		c.Render(status, render.MsgPack{Data: env})
+	case mimeCSV:
+		cols := fields
+		if len(cols) == 0 {
+			cols = csvMovieColumns
+		}
+		var extraCols []string
+		if extras != nil {
+			extraCols = extras.cols
+		}
+		cw := csv.NewWriter(c.Writer)
+		c.Header("Content-Type", mimeCSV)
+		c.Status(status)
+		cw.Write(append(append([]string{}, cols...), extraCols...))
+		for i, m := range movies {
+			record := make([]string, 0, len(cols)+len(extraCols))
+			for _, col := range cols {
+				record = append(record, csvValue(m, col))
+			}
+			for _, col := range extraCols {
+				record = append(record, fmt.Sprint(extras.values[i][col]))
+			}
+			cw.Write(record)
+		}
+		cw.Flush()
+		if err := cw.Error(); err != nil {
+			a.logger.PrintError(err, map[string]string{"msg": "unable to write movies"})
+		}
+	default:
+		// movieFormat answers 406 before the handler gets here
+		a.errorResponse(c, http.StatusInternalServerError, fmt.Errorf("unknown movie format %q", format), "")
+	}
+}
+
+// withExtras adds extra to the JSON object of v, a movie as returned by onlyFields.
+func withExtras(v any, extra map[string]any) (any, error) {
+	js, err := json.Marshal(v)
+	if err != nil {
+		return nil, err
+	}
+	var obj map[string]json.RawMessage
+	if err := json.Unmarshal(js, &obj); err != nil {
+		return nil, err
+	}
+	for k, e := range extra {
+		if obj[k], err = json.Marshal(e); err != nil {
+			return nil, err
+		}
+	}
+	return obj, nil
+}
@@ -8,33 +8,96 @@ import (
 	"github.com/go-playground/validator/v10"
 )
 
+// registerValidations adds the custom binding tags to the validator of gin.
+func (a *application) registerValidations() {
+	v := binding.Validator.Engine().(*validator.Validate)
+	v.RegisterValidation("yearrange", validation.YearRange)
+	v.RegisterValidation("runtimerange", validation.RuntimeRange)
+	v.RegisterValidation("genre", a.genres.Genres)
+	v.RegisterValidation("csvgenre", a.genres.CSVGenres)
+	v.RegisterValidation("oneof", validation.OneOf)
+	v.RegisterValidation("csvoneof", validation.CSVOneOf)
+}
+
 func (a *application) routes() *gin.Engine {
 	r := gin.Default()
-	r.Use(a.metrics(), a.rateLimiterPerHost())
+	r.HandleMethodNotAllowed = true
+	r.Use(a.requestID(), a.metrics(), a.rateLimiterPerHost())
 	r.Use(a.authenticate())
 
-	// Custom Validations
-	v := binding.Validator.Engine().(*validator.Validate)
-	v.RegisterValidation("yearrange", validation.YearRange)
-	v.RegisterValidation("genre", validation.Genres)
-	v.RegisterValidation("oneof", validation.OneOf)
+	a.registerValidations()
 
-	// r.Use(a.bodyValidationMW)
 	r.GET("/v1/healthcheck", a.healthcheckHandler)
+	r.GET("/v1/openapi.json", a.openAPIHandler(r))
 
 	//Movies API
 	movieGroup := r.Group("/v1/movies")
 	movieGroup.Use(a.requireAuthenticatedUser(), a.requireActivatedUser())
 	movieGroupRead := movieGroup.Group("")
 	movieGroupRead.Use(a.requirePermission("movies:read"))
+	movieGroupRead.GET("/search", a.searchMoviesHandler)
+	movieGroupRead.GET("/autocomplete", a.autocompleteMoviesHandler)
+	movieGroupRead.GET("/stats", a.movieStatsHandler)
+	movieGroupRead.GET("/export", a.exportMoviesHandler)
+	movieGroupRead.GET("/by-external/:source/:id", a.showMovieByExternalHandler)
 	movieGroupRead.GET("/:id", a.showMovieHandler)
 	movieGroupRead.GET("", a.listMoviesHandler)
 	movieGroupWrite := movieGroup.Group("")
 	movieGroupWrite.Use(a.requirePermission("movies:write"))
 	movieGroupWrite.POST("", a.createMovieHandler)
+	movieGroupWrite.POST("/import", a.importMoviesHandler)
+	movieGroupWrite.POST("/batch", a.batchMoviesHandler)
+	movieGroupWrite.PUT("/by-external/:source/:id", a.upsertMovieByExternalHandler)
+	movieGroupWrite.GET("/duplicates", a.listDuplicateMoviesHandler)
+	movieGroupWrite.POST("/:id/merge", a.mergeMovieHandler)
+	movieGroupWrite.PUT("/:id", a.replaceMovieHandler)
 	movieGroupWrite.PATCH("/:id", a.updateMovieHandler)
 	movieGroupWrite.DELETE("/:id", a.deleteMovieHandler)
 
+	//Genres API
+	genreGroup := r.Group("/v1/genres")
+	genreGroup.Use(a.requireAuthenticatedUser(), a.requireActivatedUser())
+	genreGroup.GET("", a.requirePermission("movies:read"), a.listGenresHandler)
+	genreGroupWrite := genreGroup.Group("")
+	genreGroupWrite.Use(a.requirePermission("genres:write"))
+	genreGroupWrite.POST("", a.createGenreHandler)
+	genreGroupWrite.PATCH("/:slug", a.updateGenreHandler)
+	genreGroupWrite.DELETE("/:slug", a.deleteGenreHandler)
+	genreGroupWrite.POST("/:slug/aliases", a.addGenreAliasHandler)
+	genreGroupWrite.DELETE("/:slug/aliases/:alias", a.removeGenreAliasHandler)
+	genreGroupWrite.POST("/:slug/merge", a.mergeGenreHandler)
+
+	//Collections API
+	collectionGroup := r.Group("/v1/collections")
+	collectionGroup.Use(a.requireAuthenticatedUser(), a.requireActivatedUser())
+	collectionGroupRead := collectionGroup.Group("")
+	collectionGroupRead.Use(a.requirePermission("movies:read"))
+	collectionGroupRead.GET("", a.listCollectionsHandler)
+	collectionGroupRead.GET("/:id", a.showCollectionHandler)
+	collectionGroupWrite := collectionGroup.Group("")
+	collectionGroupWrite.Use(a.requirePermission("movies:write"))
+	collectionGroupWrite.POST("", a.createCollectionHandler)
+	collectionGroupWrite.PATCH("/:id", a.updateCollectionHandler)
+	collectionGroupWrite.DELETE("/:id", a.deleteCollectionHandler)
+	collectionGroupWrite.POST("/:id/movies", a.addCollectionMovieHandler)
+	collectionGroupWrite.PUT("/:id/movies", a.reorderCollectionMoviesHandler)
+	collectionGroupWrite.DELETE("/:id/movies/:movie_id", a.removeCollectionMovieHandler)
+
+	//Lists API
+	listGroup := r.Group("/v1/users/me/lists")
+	listGroup.Use(a.requireAuthenticatedUser(), a.requireActivatedUser())
+	listGroup.GET("", a.listListsHandler)
+	listGroup.POST("", a.createListHandler)
+	listGroup.GET("/:id", a.showListHandler)
+	listGroup.PATCH("/:id", a.updateListHandler)
+	listGroup.DELETE("/:id", a.deleteListHandler)
+	listGroup.POST("/:id/movies", a.addListMovieHandler)
+	listGroup.PUT("/:id/movies", a.reorderListMoviesHandler)
+	listGroup.DELETE("/:id/movies/:movie_id", a.removeListMovieHandler)
+	sharedListGroup := r.Group("/v1/lists")
+	sharedListGroup.Use(a.requireAuthenticatedUser(), a.requireActivatedUser())
+	sharedListGroup.GET("/:id", a.showPublicListHandler)
+
 	r.POST("/v1/users", a.registerUserHandler)
 	r.PUT("/v1/users/activated", a.activateUserHandler)
 	r.POST("/v1/tokens/authentication", a.createAuthenticationTokenHandler)
@@ -43,20 +106,3 @@ func (a *application) routes() *gin.Engine {
 	r.NoRoute(a.noRouteHandler)
 	return r
 }
-
-// func (a *application) bodyValidationMW(c *gin.Context) {
-// 	var mv data.Movie
-// 	maxBytes := 1_048_576
-// 	body := c.Request.Body
-// 	body = http.MaxBytesReader(c.Writer, body, int64(maxBytes))
-// 	dec := json.NewDecoder(body)
-// 	dec.DisallowUnknownFields()
-// 	err := dec.Decode(&mv)
-// 	if err != nil {
-// 		c.JSON(http.StatusBadRequest, a.createError(err, "Bad req at MW"))
-// 		c.Abort()
-// 		return
-// 	}
-// 	c.Request = c.Request.WithContext(context.WithValue(c.Request.Context(), "body", body))
-// 	c.Next()
-// }
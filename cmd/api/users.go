@@ -10,14 +10,16 @@ import (
 	"github.com/gin-gonic/gin"
 )
 
+type registerUserInput struct {
+	Name     string `json:"name" binding:"required,min=2,max=255"`
+	Email    string `json:"email" binding:"required,email"`
+	Password string `json:"password" binding:"required,min=6,max=255"`
+}
+
 func (a *application) registerUserHandler(c *gin.Context) {
-	var input struct {
-		Name     string `json:"name" binding:"required,min=2,max=255"`
-		Email    string `json:"email" binding:"required,email"`
-		Password string `json:"password" binding:"required,min=6,max=255"`
-	}
-	if err := c.ShouldBindJSON(&input); err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"err": err.Error()})
+	var input registerUserInput
+	if err := a.bindJSON(c, &input); err != nil {
+		a.bodyErrorResponse(c, err)
 		return
 	}
 	user := &data.User{
@@ -26,32 +28,21 @@ func (a *application) registerUserHandler(c *gin.Context) {
 		Activated: false,
 	}
 	if err := user.Password.Set(input.Password); err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"err": err.Error()})
+		a.errorResponse(c, http.StatusInternalServerError, err, "Error while hashing password")
 		return
 	}
-	err := a.models.User.Insert(user)
-	if err != nil {
-		var dupE *data.ErrDupEmail
-		switch {
-		case errors.As(err, &dupE):
-			c.JSON(http.StatusInternalServerError, gin.H{"err": err.Error()})
-			return
-		default:
-			c.JSON(http.StatusInternalServerError, gin.H{"err": "Duplicate Email"})
-			return
-		}
-	}
-
-	err = a.models.Permission.AddForUser(user.ID, "movies:read")
+	// The user comes with its permissions and built-in lists, or not at all
+	err := a.models.User.Register(user, "movies:read")
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"err": "Failed to add permission"})
+		// A taken email is an ErrDupEmail, hence an ErrDuplicate
+		a.dataErrorResponse(c, err, "Error while inserting user")
 		return
 	}
 
 	token, err := a.models.Token.New(user.ID, 3*24*time.Hour, data.ScopeActivation)
 	if err != nil {
 		a.logger.PrintError(err, nil)
-		c.JSON(http.StatusInternalServerError, gin.H{"err": err})
+		a.errorResponse(c, http.StatusInternalServerError, err, "")
 		return
 	}
 	fmt.Printf("Token:%v\n", token)
@@ -72,69 +63,74 @@ func (a *application) registerUserHandler(c *gin.Context) {
 	c.JSON(http.StatusAccepted, gin.H{"msg": "User Created Successfully", "user": user})
 }
 
+type activateUserInput struct {
+	Token string `json:"token" binding:"required,len=26"`
+	Scope string `json:"scope" binding:"required"`
+}
+
 func (a *application) activateUserHandler(c *gin.Context) {
-	var input struct {
-		Token string `json:"token" binding:"required,len=26"`
-		Scope string `json:"scope" binding:"required"`
-	}
-	if err := c.ShouldBindJSON(&input); err != nil {
+	var input activateUserInput
+	if err := a.bindJSON(c, &input); err != nil {
 		a.logger.PrintError(err, map[string]string{"activateUserHandler": "error while binding user input"})
-		c.JSON(http.StatusBadRequest, gin.H{"err": err.Error()})
+		a.bodyErrorResponse(c, err)
 		return
 	}
 	user, err := a.models.User.GetForToken(input.Scope, input.Token)
 	if err != nil {
 		a.logger.PrintError(err, map[string]string{"activateUserHandler": "error while getting user details against token"})
-		c.JSON(http.StatusBadRequest, gin.H{"err": "Inactive or exipred token"})
+		a.errorResponse(c, http.StatusBadRequest, err, "Inactive or exipred token")
 		return
 	}
 	user.Activated = true
 	err = a.models.User.Update(user)
 	if err != nil {
 		a.logger.PrintError(err, map[string]string{"activateUserHandler": "error while updating user details post activation"})
-		c.JSON(http.StatusBadRequest, gin.H{"err": err.Error()})
+		a.dataErrorResponse(c, err, "Error while activating user")
 		return
 	}
 	err = a.models.Token.Delete(user.ID, data.ScopeActivation)
 	if err != nil {
 		a.logger.PrintError(err, map[string]string{"activateUserHandler": "error while deleting token post activation"})
-		c.JSON(http.StatusBadRequest, gin.H{"err": err.Error()})
+		a.dataErrorResponse(c, err, "Error while deleting activation token")
 		return
 	}
 	c.JSON(http.StatusOK, gin.H{"message": "User Activated", "user": user})
 }
 
+type createAuthenticationTokenInput struct {
+	Email    string `json:"email" binding:"required,email"`
+	Passowrd string `json:"password" binding:"required"`
+}
+
 func (a *application) createAuthenticationTokenHandler(c *gin.Context) {
-	var input struct {
-		Email    string `json:"email" binding:"required,email"`
-		Passowrd string `json:"password" binding:"required"`
-	}
-	if err := c.ShouldBindJSON(&input); err != nil {
+	var input createAuthenticationTokenInput
+	if err := a.bindJSON(c, &input); err != nil {
 		a.logger.PrintError(err, map[string]string{"activateTokenAuth": "error while binding user input"})
-		c.JSON(http.StatusBadRequest, gin.H{"err": err.Error()})
+		a.bodyErrorResponse(c, err)
 		return
 	}
 	user, err := a.models.User.GetByEmail(input.Email)
+	if errors.Is(err, data.ErrRecordNotFound) {
+		a.authRequiredError(c, "invalid authentication credentials")
+		return
+	}
 	if err != nil {
-		a.logger.PrintError(err, map[string]string{"activateTokenAuth": "error while getting user details by email"})
-		c.JSON(http.StatusBadRequest, gin.H{"err": err.Error()})
+		a.dataErrorResponse(c, err, "Error while reading user")
 		return
 	}
 	match, err := user.Password.Matches(input.Passowrd)
 	if err != nil {
-		a.logger.PrintError(err, map[string]string{"activateTokenAuth": "error while matching password"})
-		c.JSON(http.StatusBadRequest, gin.H{"err": err.Error()})
+		a.errorResponse(c, http.StatusInternalServerError, err, "Error while matching password")
 		return
 	}
 	if !match {
 		a.logger.PrintInfo("activateTokenAuth:Password mismatch", nil)
-		c.JSON(http.StatusBadRequest, gin.H{"err": "password mismatch"})
+		a.authRequiredError(c, "invalid authentication credentials")
 		return
 	}
 	token, err := a.models.Token.New(user.ID, 24*time.Hour, data.ScopeAuthentication)
 	if err != nil {
-		a.logger.PrintError(err, map[string]string{"activateTokenAuth": "error while generating token"})
-		c.JSON(http.StatusBadRequest, gin.H{"err": err.Error()})
+		a.dataErrorResponse(c, err, "Error while generating token")
 		return
 	}
 	c.JSON(http.StatusOK, gin.H{"msg": "Token created successfully", "token": token})
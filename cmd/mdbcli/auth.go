@@ -0,0 +1,198 @@
+package main
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"mdb/pkg/client"
+	"net/url"
+	"os"
+	"strings"
+	"text/tabwriter"
+	"time"
+)
+
+// loginCmd exchanges credentials for a token and stores it in the profile. The
+// password is read from -password, then $MDB_PASSWORD, then stdin.
+func loginCmd(ctx context.Context, a *app, args []string) error {
+	fs := flag.NewFlagSet("login", flag.ContinueOnError)
+	email := fs.String("email", "", "Email of the user")
+	password := fs.String("password", os.Getenv("MDB_PASSWORD"), "Password of the user, defaults to $MDB_PASSWORD")
+	if _, err := parseFlags(fs, args); err != nil {
+		return err
+	}
+	p, err := a.cfg.profile(a.profileName)
+	if err != nil {
+		return err
+	}
+	if *email == "" {
+		*email = p.Email
+	}
+	if *email == "" {
+		return errors.New("usage: mdbcli login -email EMAIL")
+	}
+	if *password == "" {
+		fmt.Fprint(os.Stderr, "Password: ")
+		line, err := bufio.NewReader(os.Stdin).ReadString('\n')
+		if err != nil && line == "" {
+			return fmt.Errorf("unable to read the password: %w", err)
+		}
+		*password = strings.TrimRight(line, "\r\n")
+	}
+	c, err := a.client()
+	if err != nil {
+		return err
+	}
+	token, err := c.Authenticate(ctx, *email, *password)
+	if errors.Is(err, client.ErrUnauthorized) {
+		// Not wrapped, main would suggest to log in again
+		return errors.New("invalid email or password")
+	}
+	if err != nil {
+		return err
+	}
+	p.Email, p.Token, p.TokenExpiry = *email, token.Plaintext, token.Expiry
+	if err := a.cfg.save(); err != nil {
+		return err
+	}
+	fmt.Fprintf(os.Stderr, "Logged in as %v, the token expires on %v\n", *email, token.Expiry.Local().Format("2006-01-02 15:04"))
+	return nil
+}
+
+func logoutCmd(ctx context.Context, a *app, args []string) error {
+	p, err := a.cfg.profile(a.profileName)
+	if err != nil {
+		return err
+	}
+	p.Token, p.TokenExpiry = "", time.Time{}
+	return a.cfg.save()
+}
+
+const profileUsage = "profile list | set NAME -url URL | use NAME | delete NAME"
+
+func profileCmd(ctx context.Context, a *app, args []string) error {
+	sub, args, err := subcommand(args, profileUsage)
+	if err != nil {
+		return err
+	}
+	switch sub {
+	case "list":
+		if a.output == "json" {
+			// Without the tokens, which are not meant to be copied around
+			type entry struct {
+				Name    string `json:"name"`
+				BaseURL string `json:"base_url"`
+				Email   string `json:"email,omitempty"`
+				Current bool   `json:"current"`
+			}
+			var entries []entry
+			for _, name := range a.cfg.names() {
+				p := a.cfg.Profiles[name]
+				entries = append(entries, entry{name, p.BaseURL, p.Email, name == a.cfg.Current})
+			}
+			return a.printJSON(entries)
+		}
+		tw := tabwriter.NewWriter(a.out, 0, 4, 2, ' ', 0)
+		fmt.Fprintln(tw, "\tNAME\tURL\tEMAIL\tTOKEN EXPIRY")
+		for _, name := range a.cfg.names() {
+			p := a.cfg.Profiles[name]
+			current, expiry := "", ""
+			if name == a.cfg.Current {
+				current = "*"
+			}
+			if p.Token != "" {
+				expiry = p.TokenExpiry.Local().Format("2006-01-02 15:04")
+			}
+			fmt.Fprintf(tw, "%v\t%v\t%v\t%v\t%v\n", current, name, p.BaseURL, p.Email, expiry)
+		}
+		return tw.Flush()
+	case "set":
+		fs := flag.NewFlagSet("profile set", flag.ContinueOnError)
+		baseURL := fs.String("url", "", "Base URL of the API, e.g. "+defaultBaseURL)
+		pos, err := parseFlags(fs, args)
+		if err != nil {
+			return err
+		}
+		if len(pos) != 1 || *baseURL == "" {
+			return errors.New("usage: mdbcli profile set NAME -url URL")
+		}
+		if u, err := url.Parse(*baseURL); err != nil || u.Scheme == "" || u.Host == "" {
+			return fmt.Errorf("invalid url %q", *baseURL)
+		}
+		p, ok := a.cfg.Profiles[pos[0]]
+		if !ok {
+			p = &profile{}
+			a.cfg.Profiles[pos[0]] = p
+		}
+		// A token is only valid for the API which issued it
+		if p.BaseURL != *baseURL {
+			*p = profile{BaseURL: *baseURL, Email: p.Email}
+		}
+		return a.cfg.save()
+	case "use":
+		if len(args) != 1 {
+			return errors.New("usage: mdbcli profile use NAME")
+		}
+		if _, err := a.cfg.profile(args[0]); err != nil {
+			return err
+		}
+		a.cfg.Current = args[0]
+		return a.cfg.save()
+	case "delete":
+		if len(args) != 1 {
+			return errors.New("usage: mdbcli profile delete NAME")
+		}
+		if _, err := a.cfg.profile(args[0]); err != nil {
+			return err
+		}
+		if args[0] == a.cfg.Current {
+			return errors.New("the current profile can't be deleted, use another one first")
+		}
+		delete(a.cfg.Profiles, args[0])
+		return a.cfg.save()
+	default:
+		return fmt.Errorf("usage: mdbcli %v", profileUsage)
+	}
+}
+
+const usersUsage = "users register -name NAME -email EMAIL [-password PASSWORD] | activate -token TOKEN"
+
+func usersCmd(ctx context.Context, a *app, args []string) error {
+	sub, args, err := subcommand(args, usersUsage)
+	if err != nil {
+		return err
+	}
+	c, err := a.client()
+	if err != nil {
+		return err
+	}
+	fs := flag.NewFlagSet("users "+sub, flag.ContinueOnError)
+	switch sub {
+	case "register":
+		name := fs.String("name", "", "Name of the user")
+		email := fs.String("email", "", "Email of the user, the activation token is sent to it")
+		password := fs.String("password", os.Getenv("MDB_PASSWORD"), "Password of the user, defaults to $MDB_PASSWORD")
+		if _, err := parseFlags(fs, args); err != nil {
+			return err
+		}
+		user, err := c.RegisterUser(ctx, *name, *email, *password)
+		if err != nil {
+			return err
+		}
+		return a.printUser(user)
+	case "activate":
+		token := fs.String("token", "", "Activation token received by email")
+		if _, err := parseFlags(fs, args); err != nil {
+			return err
+		}
+		user, err := c.ActivateUser(ctx, *token)
+		if err != nil {
+			return err
+		}
+		return a.printUser(user)
+	default:
+		return fmt.Errorf("usage: mdbcli %v", usersUsage)
+	}
+}
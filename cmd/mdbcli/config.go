@@ -0,0 +1,99 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"sort"
+	"time"
+)
+
+const defaultBaseURL = "http://localhost:4000"
+
+// profile is one environment of the API, along with the token obtained by login.
+type profile struct {
+	BaseURL     string    `json:"base_url"`
+	Email       string    `json:"email,omitempty"`
+	Token       string    `json:"token,omitempty"`
+	TokenExpiry time.Time `json:"token_expiry,omitempty"`
+}
+
+// config is the content of the config file, it holds tokens so it is only
+// readable by its owner.
+type config struct {
+	Current  string              `json:"current"`
+	Profiles map[string]*profile `json:"profiles"`
+
+	path string
+}
+
+// defaultConfigPath is mdbcli/config.json in the user config dir, e.g.
+// ~/.config/mdbcli/config.json on Linux.
+func defaultConfigPath() string {
+	dir, err := os.UserConfigDir()
+	if err != nil {
+		return ".mdbcli.json"
+	}
+	return filepath.Join(dir, "mdbcli", "config.json")
+}
+
+// loadConfig reads the config file, a missing file is an empty config with the
+// default profile pointing at a local API.
+func loadConfig(path string) (*config, error) {
+	cfg := &config{path: path}
+	js, err := os.ReadFile(path)
+	switch {
+	case errors.Is(err, fs.ErrNotExist):
+	case err != nil:
+		return nil, err
+	default:
+		if err := json.Unmarshal(js, cfg); err != nil {
+			return nil, fmt.Errorf("unable to read %v: %w", path, err)
+		}
+	}
+	if cfg.Profiles == nil {
+		cfg.Profiles = map[string]*profile{}
+	}
+	if cfg.Current == "" {
+		cfg.Current = "default"
+	}
+	if len(cfg.Profiles) == 0 {
+		cfg.Profiles["default"] = &profile{BaseURL: defaultBaseURL}
+	}
+	return cfg, nil
+}
+
+func (c *config) save() error {
+	js, err := json.MarshalIndent(c, "", "  ")
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(c.path), 0o700); err != nil {
+		return err
+	}
+	return os.WriteFile(c.path, append(js, '\n'), 0o600)
+}
+
+// profile returns the profile named name, or the current one when name is empty.
+func (c *config) profile(name string) (*profile, error) {
+	if name == "" {
+		name = c.Current
+	}
+	p, ok := c.Profiles[name]
+	if !ok {
+		return nil, fmt.Errorf("unknown profile %q, see mdbcli profile list", name)
+	}
+	return p, nil
+}
+
+func (c *config) names() []string {
+	names := make([]string, 0, len(c.Profiles))
+	for name := range c.Profiles {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
@@ -0,0 +1,137 @@
+// Command mdbcli manages movies and users through the API, instead of scripting
+// curl calls.
+//
+//	mdbcli profile set staging -url https://mdb.staging.example.com
+//	mdbcli -profile staging login -email admin@example.com
+//	mdbcli -profile staging movies list -genres drama -year-min 1990 -sort -year
+//	mdbcli -output json movies get 42
+//
+// Profiles and the tokens obtained by login are kept in the config file, see
+// -config.
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"mdb/pkg/client"
+	"os"
+	"os/signal"
+	"sort"
+	"strings"
+)
+
+type app struct {
+	cfg         *config
+	profileName string
+	output      string
+	out         io.Writer
+}
+
+type command struct {
+	usage string
+	run   func(ctx context.Context, a *app, args []string) error
+}
+
+var commands = map[string]command{
+	"login":   {"login -email EMAIL [-password PASSWORD]", loginCmd},
+	"logout":  {"logout", logoutCmd},
+	"profile": {profileUsage, profileCmd},
+	"movies":  {moviesUsage, moviesCmd},
+	"users":   {usersUsage, usersCmd},
+}
+
+func main() {
+	a := &app{out: os.Stdout}
+	configPath := flag.String("config", defaultConfigPath(), "Path of the config file")
+	flag.StringVar(&a.profileName, "profile", "", "Profile to use instead of the current one")
+	flag.StringVar(&a.output, "output", "table", "Output format: table or json")
+	flag.Usage = usage
+	flag.Parse()
+	if flag.NArg() == 0 {
+		usage()
+		os.Exit(2)
+	}
+	if a.output != "table" && a.output != "json" {
+		fmt.Fprintf(os.Stderr, "mdbcli: unknown output %q, use table or json\n", a.output)
+		os.Exit(2)
+	}
+	cmd, ok := commands[flag.Arg(0)]
+	if !ok {
+		fmt.Fprintf(os.Stderr, "mdbcli: unknown command %q\n", flag.Arg(0))
+		usage()
+		os.Exit(2)
+	}
+	cfg, err := loadConfig(*configPath)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, "mdbcli:", err)
+		os.Exit(1)
+	}
+	a.cfg = cfg
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+	defer stop()
+	if err := cmd.run(ctx, a, flag.Args()[1:]); err != nil {
+		stop()
+		if errors.Is(err, flag.ErrHelp) {
+			os.Exit(2)
+		}
+		fmt.Fprintln(os.Stderr, "mdbcli:", err)
+		if errors.Is(err, client.ErrUnauthorized) {
+			fmt.Fprintln(os.Stderr, "mdbcli: the token is missing or expired, run mdbcli login")
+		}
+		os.Exit(1)
+	}
+}
+
+func usage() {
+	fmt.Fprintf(os.Stderr, "Usage: mdbcli [flags] COMMAND [ARGS]\n\nCommands:\n")
+	names := make([]string, 0, len(commands))
+	for name := range commands {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		fmt.Fprintf(os.Stderr, "  %v\n", commands[name].usage)
+	}
+	fmt.Fprintf(os.Stderr, "\nFlags:\n")
+	flag.PrintDefaults()
+}
+
+// client returns a client of the API of the profile, authenticated with the token
+// stored by login.
+func (a *app) client() (*client.Client, error) {
+	p, err := a.cfg.profile(a.profileName)
+	if err != nil {
+		return nil, err
+	}
+	return client.New(p.BaseURL, client.Config{
+		Token:       p.Token,
+		TokenExpiry: p.TokenExpiry,
+		UserAgent:   "mdbcli",
+	}), nil
+}
+
+// subcommand splits args into the name of a subcommand and its arguments.
+func subcommand(args []string, usage string) (string, []string, error) {
+	if len(args) == 0 || strings.HasPrefix(args[0], "-") {
+		return "", nil, fmt.Errorf("usage: mdbcli %v", usage)
+	}
+	return args[0], args[1:], nil
+}
+
+// parseFlags parses args with fs, leading positional arguments are allowed so
+// that "get 42 -fields title" works like "get -fields title 42".
+func parseFlags(fs *flag.FlagSet, args []string) ([]string, error) {
+	var positional []string
+	for len(args) > 0 && !strings.HasPrefix(args[0], "-") {
+		positional = append(positional, args[0])
+		args = args[1:]
+	}
+	if err := fs.Parse(args); err != nil {
+		return nil, err
+	}
+	return append(positional, fs.Args()...), nil
+}
@@ -0,0 +1,178 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"mdb/internal/data"
+	"strconv"
+	"strings"
+)
+
+const moviesUsage = "movies list [FILTERS] | get ID [-fields F,...] | create -title T -year Y -runtime MINS -genres G,... | update ID [-title T] [-year Y] [-runtime MINS] [-genres G,...] | delete ID"
+
+func moviesCmd(ctx context.Context, a *app, args []string) error {
+	sub, args, err := subcommand(args, moviesUsage)
+	if err != nil {
+		return err
+	}
+	c, err := a.client()
+	if err != nil {
+		return err
+	}
+	fs := flag.NewFlagSet("movies "+sub, flag.ContinueOnError)
+	switch sub {
+	case "list":
+		var lm data.ListMovie
+		genres, facets, fields := listFlag{}, listFlag{}, listFlag{}
+		runtimeMin, runtimeMax := fs.Int("runtime-min", 0, "Shortest runtime, in minutes"), fs.Int("runtime-max", 0, "Longest runtime, in minutes")
+		yearMin, yearMax := fs.Int("year-min", 0, "Earliest year"), fs.Int("year-max", 0, "Latest year")
+		fs.StringVar(&lm.Title, "title", "", "Words of the title")
+		fs.Var(&genres, "genres", "Comma separated genres")
+		fs.StringVar(&lm.GenreMatch, "genre-match", "", "Whether movies need any or all of the genres")
+		fs.Int64Var(&lm.Collection, "collection", 0, "Id of a collection the movies belong to")
+		fs.Var(&facets, "facets", "Comma separated facets to count: genres, decade, runtime")
+		fs.Var(&fields, "fields", "Comma separated fields to show")
+		fs.IntVar(&lm.Page, "page", 0, "Page number")
+		fs.IntVar(&lm.PageSize, "page-size", 0, "Movies per page")
+		fs.StringVar(&lm.Sort, "sort", "", "Sort column, prefixed with - for descending order")
+		fs.StringVar(&lm.Cursor, "cursor", "", "Cursor of the page, from a previous list")
+		fs.StringVar(&lm.Pagination, "pagination", "", "page or cursor")
+		if _, err := parseFlags(fs, args); err != nil {
+			return err
+		}
+		lm.Genres, lm.Facets, lm.Fields = genres, facets, fields
+		lm.YearMin, lm.YearMax = int32(*yearMin), int32(*yearMax)
+		lm.RuntimeMin, lm.RuntimeMax = data.Runtime(*runtimeMin), data.Runtime(*runtimeMax)
+		list, err := c.ListMovies(ctx, lm)
+		if err != nil {
+			return err
+		}
+		return a.printMovies(list, fields)
+	case "get":
+		var fields listFlag
+		fs.Var(&fields, "fields", "Comma separated fields to show")
+		id, err := parseID(fs, args)
+		if err != nil {
+			return err
+		}
+		details, err := c.GetMovie(ctx, id, fields...)
+		if err != nil {
+			return err
+		}
+		if a.output == "json" {
+			return a.printJSON(details)
+		}
+		return a.printMovieTable(fields, details.Movie)
+	case "create":
+		var in movieFlags
+		in.define(fs)
+		if _, err := parseFlags(fs, args); err != nil {
+			return err
+		}
+		movie, err := c.CreateMovie(ctx, &data.Movie{Title: in.title, Year: int32(in.year), Runtime: data.Runtime(in.runtime), Genres: in.genres})
+		if err != nil {
+			return err
+		}
+		return a.printMovie(movie)
+	case "update":
+		var in movieFlags
+		in.define(fs)
+		id, err := parseID(fs, args)
+		if err != nil {
+			return err
+		}
+		update := in.update(fs)
+		if update.Title == nil && update.Year == nil && update.Runtime == nil && update.Genres == nil {
+			return errors.New("nothing to update, set -title, -year, -runtime or -genres")
+		}
+		movie, err := c.UpdateMovie(ctx, id, update)
+		if err != nil {
+			return err
+		}
+		return a.printMovie(movie)
+	case "delete":
+		id, err := parseID(fs, args)
+		if err != nil {
+			return err
+		}
+		if err := c.DeleteMovie(ctx, id); err != nil {
+			return err
+		}
+		if a.output == "json" {
+			return a.printJSON(map[string]int64{"deleted": id})
+		}
+		fmt.Fprintf(a.out, "Movie %d deleted\n", id)
+		return nil
+	default:
+		return fmt.Errorf("usage: mdbcli %v", moviesUsage)
+	}
+}
+
+// parseID parses the flags of a subcommand taking a movie id.
+func parseID(fs *flag.FlagSet, args []string) (int64, error) {
+	pos, err := parseFlags(fs, args)
+	if err != nil {
+		return 0, err
+	}
+	if len(pos) != 1 {
+		return 0, fmt.Errorf("usage: mdbcli %v ID", fs.Name())
+	}
+	id, err := strconv.ParseInt(pos[0], 10, 64)
+	if err != nil || id < 1 {
+		return 0, fmt.Errorf("invalid id %q", pos[0])
+	}
+	return id, nil
+}
+
+// movieFlags are the fields of a movie for create and update.
+type movieFlags struct {
+	title   string
+	year    int
+	runtime int
+	genres  listFlag
+}
+
+func (m *movieFlags) define(fs *flag.FlagSet) {
+	fs.StringVar(&m.title, "title", "", "Title")
+	fs.IntVar(&m.year, "year", 0, "Year of release")
+	fs.IntVar(&m.runtime, "runtime", 0, "Runtime, in minutes")
+	fs.Var(&m.genres, "genres", "Comma separated genres")
+}
+
+// update holds the flags which were set, the others are left alone.
+func (m *movieFlags) update(fs *flag.FlagSet) data.MovieUpdate {
+	var u data.MovieUpdate
+	fs.Visit(func(f *flag.Flag) {
+		switch f.Name {
+		case "title":
+			u.Title = &m.title
+		case "year":
+			year := int32(m.year)
+			u.Year = &year
+		case "runtime":
+			runtime := data.Runtime(m.runtime)
+			u.Runtime = &runtime
+		case "genres":
+			u.Genres = m.genres
+		}
+	})
+	return u
+}
+
+// listFlag is a comma separated flag, it can also be repeated.
+type listFlag []string
+
+func (l *listFlag) String() string {
+	return strings.Join(*l, ",")
+}
+
+func (l *listFlag) Set(v string) error {
+	for _, s := range strings.Split(v, ",") {
+		if s = strings.TrimSpace(s); s != "" {
+			*l = append(*l, s)
+		}
+	}
+	return nil
+}
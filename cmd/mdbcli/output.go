@@ -0,0 +1,114 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"mdb/internal/data"
+	"mdb/pkg/client"
+	"sort"
+	"strconv"
+	"strings"
+	"text/tabwriter"
+)
+
+// Columns of the movie tables when no fields are picked
+var movieColumns = []string{"id", "title", "year", "runtime", "genres", "version"}
+
+func (a *app) printJSON(v any) error {
+	enc := json.NewEncoder(a.out)
+	enc.SetIndent("", "  ")
+	return enc.Encode(v)
+}
+
+func (a *app) printMovie(movie *data.Movie) error {
+	if a.output == "json" {
+		return a.printJSON(movie)
+	}
+	return a.printMovieTable(nil, movie)
+}
+
+// printMovies prints a page of movies followed by how to get the next one.
+func (a *app) printMovies(list *client.MovieList, fields []string) error {
+	if a.output == "json" {
+		return a.printJSON(list)
+	}
+	if err := a.printMovieTable(fields, list.Movies...); err != nil {
+		return err
+	}
+	md := list.Metadata
+	switch {
+	case md.NextCursor != "":
+		fmt.Fprintf(a.out, "\nNext page: -cursor %v\n", md.NextCursor)
+	case md.LastPage > 0:
+		fmt.Fprintf(a.out, "\nPage %d of %d, %d movies\n", md.CurrentPage, md.LastPage, md.TotalRecords)
+	}
+	for _, facet := range sortedKeys(list.Facets) {
+		var counts []string
+		for _, fc := range list.Facets[facet] {
+			counts = append(counts, fmt.Sprintf("%v (%d)", fc.Value, fc.Count))
+		}
+		fmt.Fprintf(a.out, "%v: %v\n", facet, strings.Join(counts, ", "))
+	}
+	return nil
+}
+
+func (a *app) printMovieTable(fields []string, movies ...*data.Movie) error {
+	cols := fields
+	if len(cols) == 0 {
+		cols = movieColumns
+	}
+	tw := tabwriter.NewWriter(a.out, 0, 4, 2, ' ', 0)
+	fmt.Fprintln(tw, strings.ToUpper(strings.Join(cols, "\t")))
+	for _, m := range movies {
+		values := make([]string, len(cols))
+		for i, col := range cols {
+			values[i] = movieValue(m, col)
+		}
+		fmt.Fprintln(tw, strings.Join(values, "\t"))
+	}
+	return tw.Flush()
+}
+
+func movieValue(m *data.Movie, col string) string {
+	switch col {
+	case "id":
+		return strconv.FormatInt(m.ID, 10)
+	case "title":
+		return m.Title
+	case "year":
+		return strconv.Itoa(int(m.Year))
+	case "runtime":
+		return fmt.Sprintf("%d mins", m.Runtime)
+	case "genres":
+		return strings.Join(m.Genres, ",")
+	case "version":
+		return strconv.Itoa(int(m.Version))
+	case "external_ids":
+		var ids []string
+		for _, source := range sortedKeys(m.ExternalIDs) {
+			ids = append(ids, source+":"+m.ExternalIDs[source])
+		}
+		return strings.Join(ids, ",")
+	default:
+		return ""
+	}
+}
+
+func (a *app) printUser(user *data.User) error {
+	if a.output == "json" {
+		return a.printJSON(user)
+	}
+	tw := tabwriter.NewWriter(a.out, 0, 4, 2, ' ', 0)
+	fmt.Fprintln(tw, "ID\tNAME\tEMAIL\tACTIVATED")
+	fmt.Fprintf(tw, "%d\t%v\t%v\t%v\n", user.ID, user.Name, user.Email, user.Activated)
+	return tw.Flush()
+}
+
+func sortedKeys[V any](m map[string]V) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
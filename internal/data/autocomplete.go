@@ -0,0 +1,102 @@
+package data
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Autocomplete answers within a much tighter budget than the other queries, a
+// suggestion arriving late is useless anyway.
+const autocompleteTimeout = 500 * time.Millisecond
+
+// maxCachedPrefixes bounds the memory used by the cache, once reached the cache
+// simply starts over.
+const maxCachedPrefixes = 10000
+
+type Suggestion struct {
+	ID    int64  `json:"id"`
+	Title string `json:"title"`
+	Year  int32  `json:"year"`
+}
+
+// suggestionCache keeps the suggestions per prefix. Any write to movies may change
+// the answer for any prefix, hence MovieModel clears it on Insert, Update and Delete.
+type suggestionCache struct {
+	mu      sync.RWMutex
+	entries map[string][]*Suggestion
+}
+
+func newSuggestionCache() *suggestionCache {
+	return &suggestionCache{entries: map[string][]*Suggestion{}}
+}
+
+func (s *suggestionCache) get(key string) ([]*Suggestion, bool) {
+	if s == nil {
+		return nil, false
+	}
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	v, ok := s.entries[key]
+	return v, ok
+}
+
+func (s *suggestionCache) set(key string, v []*Suggestion) {
+	if s == nil {
+		return
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if len(s.entries) >= maxCachedPrefixes {
+		s.entries = map[string][]*Suggestion{}
+	}
+	s.entries[key] = v
+}
+
+func (s *suggestionCache) clear() {
+	if s == nil {
+		return
+	}
+	s.mu.Lock()
+	s.entries = map[string][]*Suggestion{}
+	s.mu.Unlock()
+}
+
+// Autocomplete returns up to limit movies whose title starts with prefix, ignoring
+// case. It is backed by the text_pattern_ops index on lower(title).
+func (m MovieModel) Autocomplete(prefix string, limit int) ([]*Suggestion, error) {
+	prefix = strings.ToLower(prefix)
+	key := fmt.Sprintf("%d:%v", limit, prefix)
+	if suggestions, ok := m.suggestions.get(key); ok {
+		return suggestions, nil
+	}
+	// Escape the LIKE wildcards so that they match literally
+	pattern := strings.NewReplacer(`\`, `\\`, `%`, `\%`, `_`, `\_`).Replace(prefix) + "%"
+	query := `SELECT id, title, year
+	FROM movies
+	WHERE lower(title) LIKE $1
+	ORDER BY lower(title) ASC, id ASC
+	LIMIT $2`
+	ctx, cancel := context.WithTimeout(context.Background(), autocompleteTimeout)
+	defer cancel()
+	rows, err := m.DB.QueryContext(ctx, query, pattern, limit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	suggestions := []*Suggestion{}
+	for rows.Next() {
+		var s Suggestion
+		if err := rows.Scan(&s.ID, &s.Title, &s.Year); err != nil {
+			return nil, err
+		}
+		suggestions = append(suggestions, &s)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	m.suggestions.set(key, suggestions)
+	return suggestions, nil
+}
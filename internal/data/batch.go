@@ -0,0 +1,158 @@
+package data
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/lib/pq"
+)
+
+const (
+	BatchCreate = "create"
+	BatchUpdate = "update"
+	BatchDelete = "delete"
+)
+
+// MovieUpdate holds the fields of a partial update, nil fields are left alone.
+type MovieUpdate struct {
+	Title   *string  `json:"title,omitempty"`
+	Year    *int32   `json:"year,omitempty"`
+	Runtime *Runtime `json:"runtime,omitempty"`
+	Genres  []string `json:"genres,omitempty"`
+}
+
+func (u MovieUpdate) apply(movie *Movie) {
+	if u.Title != nil {
+		movie.Title = *u.Title
+	}
+	if u.Year != nil {
+		movie.Year = *u.Year
+	}
+	if u.Runtime != nil {
+		movie.Runtime = *u.Runtime
+	}
+	if u.Genres != nil {
+		movie.Genres = u.Genres
+	}
+}
+
+// BatchOp is one operation of MovieModel.Batch. Movie is the movie to create for
+// BatchCreate, Update and the expected Version apply to BatchUpdate. Once the batch
+// ran, Movie holds the resulting movie and Err the reason the operation failed.
+type BatchOp struct {
+	Op      string
+	ID      int64
+	Version int32
+	Movie   *Movie
+	Update  *MovieUpdate
+	Err     error
+}
+
+// Batch runs the operations in a single transaction. When atomic, the first failing
+// operation rolls back the whole batch, otherwise failing operations are undone
+// on their own and the others are committed. Operations which already carry an Err
+// (e.g. they failed validation) are not run. The returned error is only set for
+// failures which are not about one operation, like a lost connection.
+func (m MovieModel) Batch(ops []*BatchOp, atomic bool) error {
+	ctx, cancel := context.WithTimeout(context.Background(), batchTimeout*time.Second)
+	defer cancel()
+	tx, err := m.DB.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+	for _, op := range ops {
+		if op.Err != nil {
+			continue
+		}
+		if _, err := tx.ExecContext(ctx, `SAVEPOINT batch_op`); err != nil {
+			return err
+		}
+		err := batchApply(ctx, tx, op)
+		if err == nil {
+			continue
+		}
+		var pqErr *pq.Error
+		if !errors.Is(err, ErrRecordNotFound) && !errors.Is(err, ErrEditConflict) && !errors.As(err, &pqErr) {
+			return err
+		}
+		op.Err = err
+		if atomic {
+			return nil
+		}
+		if _, err := tx.ExecContext(ctx, `ROLLBACK TO SAVEPOINT batch_op`); err != nil {
+			return err
+		}
+	}
+	if err := tx.Commit(); err != nil {
+		return err
+	}
+	m.suggestions.clear()
+	return nil
+}
+
+func batchApply(ctx context.Context, tx *sql.Tx, op *BatchOp) error {
+	switch op.Op {
+	case BatchCreate:
+		query := `INSERT INTO movies (title, year, runtime, genres)
+		VALUES ($1, $2, $3, $4)
+		RETURNING id, created_at, version`
+		args := []interface{}{op.Movie.Title, op.Movie.Year, op.Movie.Runtime, pq.Array(op.Movie.Genres)}
+		return tx.QueryRowContext(ctx, query, args...).Scan(&op.Movie.ID, &op.Movie.CreatedAt, &op.Movie.Version)
+	case BatchUpdate:
+		var movie Movie
+		query := `SELECT id, created_at, title, year, runtime, genres, version
+		FROM movies
+		WHERE id = $1
+		FOR UPDATE`
+		err := tx.QueryRowContext(ctx, query, op.ID).Scan(
+			&movie.ID,
+			&movie.CreatedAt,
+			&movie.Title,
+			&movie.Year,
+			&movie.Runtime,
+			pq.Array(&movie.Genres),
+			&movie.Version,
+		)
+		if err != nil {
+			switch {
+			case errors.Is(err, sql.ErrNoRows):
+				return fmt.Errorf("%w", ErrRecordNotFound)
+			default:
+				return err
+			}
+		}
+		if movie.Version != op.Version {
+			return fmt.Errorf("%w", ErrEditConflict)
+		}
+		op.Update.apply(&movie)
+		query = `UPDATE movies
+		SET title = $1, year = $2, runtime = $3, genres = $4, version = version + 1
+		WHERE id = $5 AND version = $6
+		RETURNING version`
+		args := []interface{}{movie.Title, movie.Year, movie.Runtime, pq.Array(movie.Genres), movie.ID, movie.Version}
+		if err := tx.QueryRowContext(ctx, query, args...).Scan(&movie.Version); err != nil {
+			return err
+		}
+		op.Movie = &movie
+		return nil
+	case BatchDelete:
+		r, err := tx.ExecContext(ctx, `DELETE FROM movies WHERE id = $1`, op.ID)
+		if err != nil {
+			return err
+		}
+		rowsAffected, err := r.RowsAffected()
+		if err != nil {
+			return err
+		}
+		if rowsAffected == 0 {
+			return fmt.Errorf("%w", ErrRecordNotFound)
+		}
+		return nil
+	default:
+		return fmt.Errorf("unknown batch operation %q", op.Op)
+	}
+}
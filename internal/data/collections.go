@@ -0,0 +1,213 @@
+package data
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/lib/pq"
+)
+
+type CollectionModel struct {
+	DB *sql.DB
+}
+
+func (m CollectionModel) Insert(collection *Collection) error {
+	query := `INSERT INTO collections (name, description)
+	VALUES ($1, $2)
+	RETURNING id, created_at, version`
+	ctx, cancel := context.WithTimeout(context.Background(), timeout*time.Second)
+	defer cancel()
+	// Names are unique
+	err := m.DB.QueryRowContext(ctx, query, collection.Name, collection.Description).Scan(
+		&collection.ID, &collection.CreatedAt, &collection.Version)
+	return dbError(err)
+}
+
+func (m CollectionModel) Get(id int64) (*Collection, error) {
+	if id < 1 {
+		return nil, fmt.Errorf("%w", ErrRecordNotFound)
+	}
+	query := `SELECT id, created_at, name, description, version
+	FROM collections
+	WHERE id = $1`
+	var collection Collection
+	ctx, cancel := context.WithTimeout(context.Background(), timeout*time.Second)
+	defer cancel()
+	err := m.DB.QueryRowContext(ctx, query, id).Scan(
+		&collection.ID,
+		&collection.CreatedAt,
+		&collection.Name,
+		&collection.Description,
+		&collection.Version,
+	)
+	if err != nil {
+		switch {
+		case errors.Is(err, sql.ErrNoRows):
+			return nil, fmt.Errorf("%w", ErrRecordNotFound)
+		default:
+			return nil, err
+		}
+	}
+	return &collection, nil
+}
+
+func (m CollectionModel) GetAll() ([]*Collection, error) {
+	query := `SELECT id, created_at, name, description, version
+	FROM collections
+	ORDER BY name ASC, id ASC`
+	return m.query(query)
+}
+
+// GetAllForMovie returns every collection the movie is a member of.
+func (m CollectionModel) GetAllForMovie(movieID int64) ([]*Collection, error) {
+	query := `SELECT collections.id, collections.created_at, collections.name,
+	collections.description, collections.version
+	FROM collections
+	INNER JOIN collections_movies ON collections_movies.collection_id = collections.id
+	WHERE collections_movies.movie_id = $1
+	ORDER BY collections.name ASC, collections.id ASC`
+	return m.query(query, movieID)
+}
+
+func (m CollectionModel) query(query string, args ...interface{}) ([]*Collection, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), timeout*time.Second)
+	defer cancel()
+	rows, err := m.DB.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	collections := []*Collection{}
+	for rows.Next() {
+		var collection Collection
+		err := rows.Scan(
+			&collection.ID,
+			&collection.CreatedAt,
+			&collection.Name,
+			&collection.Description,
+			&collection.Version,
+		)
+		if err != nil {
+			return nil, err
+		}
+		collections = append(collections, &collection)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return collections, nil
+}
+
+func (m CollectionModel) Update(collection *Collection) error {
+	// Same optimistic locking as MovieModel.Update
+	query := `UPDATE collections
+	SET name = $1, description = $2, version = version + 1
+	WHERE id = $3 AND version = $4
+	RETURNING version`
+	args := []interface{}{collection.Name, collection.Description, collection.ID, collection.Version}
+	ctx, cancel := context.WithTimeout(context.Background(), timeout*time.Second)
+	defer cancel()
+	err := m.DB.QueryRowContext(ctx, query, args...).Scan(&collection.Version)
+	if errors.Is(err, sql.ErrNoRows) {
+		return fmt.Errorf("%w", ErrEditConflict)
+	}
+	return dbError(err)
+}
+
+func (m CollectionModel) Delete(id int64) error {
+	if id < 1 {
+		return fmt.Errorf("%w", ErrRecordNotFound)
+	}
+	query := `DELETE FROM collections WHERE id = $1`
+	ctx, cancel := context.WithTimeout(context.Background(), timeout*time.Second)
+	defer cancel()
+	r, err := m.DB.ExecContext(ctx, query, id)
+	if err != nil {
+		return err
+	}
+	rowsAffected, err := r.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if rowsAffected == 0 {
+		return fmt.Errorf("%w", ErrRecordNotFound)
+	}
+	return nil
+}
+
+// AddMovie appends the movie at the end of the collection. Adding a movie which
+// is already a member is a no-op.
+func (m CollectionModel) AddMovie(collectionID, movieID int64) error {
+	query := `INSERT INTO collections_movies (collection_id, movie_id, position)
+	SELECT $1, $2, COALESCE(MAX(position), 0) + 1 FROM collections_movies WHERE collection_id = $1
+	ON CONFLICT (collection_id, movie_id) DO NOTHING`
+	ctx, cancel := context.WithTimeout(context.Background(), timeout*time.Second)
+	defer cancel()
+	_, err := m.DB.ExecContext(ctx, query, collectionID, movieID)
+	return err
+}
+
+func (m CollectionModel) RemoveMovie(collectionID, movieID int64) error {
+	query := `DELETE FROM collections_movies WHERE collection_id = $1 AND movie_id = $2`
+	ctx, cancel := context.WithTimeout(context.Background(), timeout*time.Second)
+	defer cancel()
+	r, err := m.DB.ExecContext(ctx, query, collectionID, movieID)
+	if err != nil {
+		return err
+	}
+	rowsAffected, err := r.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if rowsAffected == 0 {
+		return fmt.Errorf("%w", ErrRecordNotFound)
+	}
+	return nil
+}
+
+func (m CollectionModel) Reorder(collectionID int64, movieIDs []int64) error {
+	return reorderMembers(m.DB, "collections_movies", "collection_id", collectionID, movieIDs)
+}
+
+// GetMovies returns every movie of the collection in collection order. Collections
+// are small (a franchise rarely has more than a couple of dozen movies), hence
+// there is no pagination.
+func (m CollectionModel) GetMovies(collectionID int64) ([]*Movie, error) {
+	query := `SELECT movies.id, movies.created_at, movies.title, movies.year,
+	movies.runtime, movies.genres, movies.version
+	FROM movies
+	INNER JOIN collections_movies ON collections_movies.movie_id = movies.id
+	WHERE collections_movies.collection_id = $1
+	ORDER BY collections_movies.position ASC, movies.id ASC`
+	ctx, cancel := context.WithTimeout(context.Background(), timeout*time.Second)
+	defer cancel()
+	rows, err := m.DB.QueryContext(ctx, query, collectionID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	movies := []*Movie{}
+	for rows.Next() {
+		var movie Movie
+		err := rows.Scan(
+			&movie.ID,
+			&movie.CreatedAt,
+			&movie.Title,
+			&movie.Year,
+			&movie.Runtime,
+			pq.Array(&movie.Genres),
+			&movie.Version,
+		)
+		if err != nil {
+			return nil, err
+		}
+		movies = append(movies, &movie)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return movies, nil
+}
@@ -0,0 +1,99 @@
+package data
+
+import (
+	"encoding/base64"
+	"errors"
+	"strings"
+	"testing"
+)
+
+var testCursorKey = []byte("0123456789abcdef0123456789abcdef")
+
+func TestCursorRoundTrip(t *testing.T) {
+	movie := &Movie{ID: 42, Title: "Casablanca", Year: 1942, Runtime: 102}
+	for _, sort := range []string{"id", "-title", "year", "-runtime"} {
+		want := newCursor(sort, "digest", movie, cursorPrev)
+		got, err := decodeCursor(want.encode(testCursorKey), testCursorKey)
+		if err != nil {
+			t.Fatalf("sort %v: unable to decode the cursor: %v", sort, err)
+		}
+		if *got != want {
+			t.Errorf("sort %v: got %+v, want %+v", sort, *got, want)
+		}
+		if _, err := got.value(); err != nil {
+			t.Errorf("sort %v: unable to read the value: %v", sort, err)
+		}
+	}
+}
+
+func TestCursorTampered(t *testing.T) {
+	movie := &Movie{ID: 42, Title: "Casablanca", Year: 1942, Runtime: 102}
+	valid := newCursor("-year", "digest", movie, cursorNext).encode(testCursorKey)
+	payload, sig, _ := strings.Cut(valid, ".")
+	enc := base64.RawURLEncoding
+	js, _ := enc.DecodeString(payload)
+	forged := enc.EncodeToString([]byte(strings.Replace(string(js), "1942", "2042", 1)))
+
+	tests := map[string]string{
+		"empty":           "",
+		"no signature":    payload,
+		"bad encoding":    payload + ".!!",
+		"forged payload":  forged + "." + sig,
+		"other signature": payload + "." + enc.EncodeToString(make([]byte, 32)),
+		"extra part":      valid + ".x",
+	}
+	for name, s := range tests {
+		if _, err := decodeCursor(s, testCursorKey); !errors.Is(err, ErrInvalidCursor) {
+			t.Errorf("%v: got %v, want ErrInvalidCursor", name, err)
+		}
+	}
+	if _, err := decodeCursor(valid, []byte("another key")); !errors.Is(err, ErrInvalidCursor) {
+		t.Errorf("other key: got %v, want ErrInvalidCursor", err)
+	}
+}
+
+func TestFilterDigest(t *testing.T) {
+	base := ListMovie{Title: "star", Genres: []string{"drama", "sci-fi"}, YearMin: 1970}
+	same := base
+	same.Genres = []string{"sci-fi", "drama"}
+	same.Fields = []string{"title"}
+	same.Filters = Filters{Page: 3, PageSize: 5, Sort: "-year"}
+	if base.filterDigest() != same.filterDigest() {
+		t.Error("the digest depends on the order of the genres or on pagination")
+	}
+	explicit := base
+	explicit.GenreMatch = "all"
+	if base.filterDigest() != explicit.filterDigest() {
+		t.Error("the default genre match has another digest than all")
+	}
+
+	changes := map[string]func(*ListMovie){
+		"title":       func(lm *ListMovie) { lm.Title = "wars" },
+		"genres":      func(lm *ListMovie) { lm.Genres = []string{"drama"} },
+		"genre match": func(lm *ListMovie) { lm.GenreMatch = "any" },
+		"collection":  func(lm *ListMovie) { lm.Collection = 1 },
+		"year":        func(lm *ListMovie) { lm.YearMax = 1980 },
+		"runtime":     func(lm *ListMovie) { lm.RuntimeMin = 90 },
+	}
+	for name, change := range changes {
+		lm := base
+		change(&lm)
+		if lm.filterDigest() == base.filterDigest() {
+			t.Errorf("changing the %v keeps the digest", name)
+		}
+	}
+}
+
+func TestOrderBy(t *testing.T) {
+	tests := map[string]string{
+		"title":    "title ASC, id ASC",
+		"-title":   "title DESC, id DESC",
+		"-runtime": "runtime DESC, id DESC",
+	}
+	for sort, want := range tests {
+		f := Filters{Sort: sort}
+		if got := f.orderBy(); got != want {
+			t.Errorf("sort %v: got %q, want %q", sort, got, want)
+		}
+	}
+}
@@ -0,0 +1,215 @@
+package data
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/lib/pq"
+)
+
+type DuplicateCandidate struct {
+	Movie      *Movie  `json:"movie"`
+	Duplicate  *Movie  `json:"duplicate"`
+	Similarity float64 `json:"similarity"`
+}
+
+// movieRefs lists the tables pointing at movies along with the column which,
+// together with movie_id, has to stay unique. Rows of the source which the target
+// already has are dropped by Merge. External ids are not part of it, they are
+// never dropped, see ErrExternalIDClash.
+var movieRefs = []struct{ table, keyCol string }{
+	{"lists_movies", "list_id"},
+	{"collections_movies", "collection_id"},
+}
+
+// FindDuplicates pairs movies whose titles are at least threshold similar (pg_trgm
+// similarity, between 0 and 1) and whose years and runtimes are within the given
+// tolerances. The pair is reported once, the movie with the lower id first.
+func (m MovieModel) FindDuplicates(threshold float64, yearTolerance, runtimeTolerance int, filters Filters) ([]*DuplicateCandidate, *Metadata, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), batchTimeout*time.Second)
+	defer cancel()
+	tx, err := m.DB.BeginTx(ctx, &sql.TxOptions{ReadOnly: true})
+	if err != nil {
+		return nil, nil, err
+	}
+	defer tx.Rollback()
+	// The % operator can use the trigram index, unlike a similarity() >= $1 filter,
+	// hence the threshold is set for the transaction instead.
+	if _, err := tx.ExecContext(ctx, `SELECT set_config('pg_trgm.similarity_threshold', $1, true)`, fmt.Sprint(threshold)); err != nil {
+		return nil, nil, err
+	}
+	query := `SELECT count(*) OVER(), similarity(a.title, b.title),
+	a.id, a.created_at, a.title, a.year, a.runtime, a.genres, a.version,
+	b.id, b.created_at, b.title, b.year, b.runtime, b.genres, b.version
+	FROM movies AS a
+	INNER JOIN movies AS b ON a.title % b.title AND a.id < b.id
+	WHERE abs(a.year - b.year) <= $1 AND abs(a.runtime - b.runtime) <= $2
+	ORDER BY similarity(a.title, b.title) DESC, a.id ASC, b.id ASC
+	LIMIT $3 OFFSET $4`
+	rows, err := tx.QueryContext(ctx, query, yearTolerance, runtimeTolerance, filters.limit(), filters.offset())
+	if err != nil {
+		return nil, nil, err
+	}
+	defer rows.Close()
+	tr := 0
+	candidates := []*DuplicateCandidate{}
+	for rows.Next() {
+		a, b := &Movie{}, &Movie{}
+		dc := DuplicateCandidate{Movie: a, Duplicate: b}
+		err := rows.Scan(
+			&tr,
+			&dc.Similarity,
+			&a.ID, &a.CreatedAt, &a.Title, &a.Year, &a.Runtime, pq.Array(&a.Genres), &a.Version,
+			&b.ID, &b.CreatedAt, &b.Title, &b.Year, &b.Runtime, pq.Array(&b.Genres), &b.Version,
+		)
+		if err != nil {
+			return nil, nil, err
+		}
+		candidates = append(candidates, &dc)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, nil, err
+	}
+	metadata := calculateMetadata(tr, filters.Page, filters.PageSize)
+	return candidates, &metadata, nil
+}
+
+// Merge folds the movie sourceID into targetID: the target takes genres, or when
+// genres is nil gains the genres of the source it lacks, list, collection and
+// external id rows move to the target, then the source is deleted. Genres going
+// over the five allowed by genres_length_check are an ErrTooManyGenres.
+// Everything happens in one transaction. A movie has one id per source, hence when
+// both movies have an id of the same source the merge is refused with an
+// ErrExternalIDClash rather than losing one of them.
+func (m MovieModel) Merge(targetID, sourceID int64, genres []string) (*Movie, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), timeout*time.Second)
+	defer cancel()
+	tx, err := m.DB.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	query := `SELECT id, created_at, title, year, runtime, genres, version
+	FROM movies
+	WHERE id = $1
+	FOR UPDATE`
+	var target, source Movie
+	locks := []struct {
+		id    int64
+		movie *Movie
+	}{{targetID, &target}, {sourceID, &source}}
+	// Rows are locked by ascending id, so that merging A into B while B is merged
+	// into A can't deadlock
+	if sourceID < targetID {
+		locks[0], locks[1] = locks[1], locks[0]
+	}
+	for _, mv := range locks {
+		err := tx.QueryRowContext(ctx, query, mv.id).Scan(
+			&mv.movie.ID,
+			&mv.movie.CreatedAt,
+			&mv.movie.Title,
+			&mv.movie.Year,
+			&mv.movie.Runtime,
+			pq.Array(&mv.movie.Genres),
+			&mv.movie.Version,
+		)
+		if err != nil {
+			switch {
+			case errors.Is(err, sql.ErrNoRows):
+				return nil, fmt.Errorf("%w", ErrRecordNotFound)
+			default:
+				return nil, err
+			}
+		}
+	}
+
+	clashes, err := externalIDClashes(ctx, tx, targetID, sourceID)
+	if err != nil {
+		return nil, err
+	}
+	if len(clashes) > 0 {
+		return nil, &ErrExternalIDClash{clashes}
+	}
+
+	if genres == nil {
+		genres = target.Genres
+		for _, g := range source.Genres {
+			found := false
+			for _, t := range genres {
+				if t == g {
+					found = true
+					break
+				}
+			}
+			if !found {
+				genres = append(genres, g)
+			}
+		}
+		if len(genres) > 5 {
+			return nil, &ErrTooManyGenres{genres}
+		}
+	}
+	target.Genres = genres
+
+	for _, ref := range movieRefs {
+		// Rows the target already has would collide once re-pointed
+		query := fmt.Sprintf(`DELETE FROM %[1]v
+		WHERE movie_id = $1 AND %[2]v IN (SELECT %[2]v FROM %[1]v WHERE movie_id = $2)`, ref.table, ref.keyCol)
+		if _, err := tx.ExecContext(ctx, query, sourceID, targetID); err != nil {
+			return nil, err
+		}
+		query = fmt.Sprintf(`UPDATE %v SET movie_id = $1 WHERE movie_id = $2`, ref.table)
+		if _, err := tx.ExecContext(ctx, query, targetID, sourceID); err != nil {
+			return nil, err
+		}
+	}
+	query = `UPDATE movie_external_ids SET movie_id = $1 WHERE movie_id = $2`
+	if _, err := tx.ExecContext(ctx, query, targetID, sourceID); err != nil {
+		return nil, err
+	}
+
+	query = `UPDATE movies SET genres = $1, version = version + 1 WHERE id = $2 RETURNING version`
+	if err := tx.QueryRowContext(ctx, query, pq.Array(target.Genres), targetID).Scan(&target.Version); err != nil {
+		return nil, err
+	}
+	if _, err := tx.ExecContext(ctx, `DELETE FROM movies WHERE id = $1`, sourceID); err != nil {
+		return nil, err
+	}
+	err = tx.QueryRowContext(ctx, `SELECT `+externalIDsCol+` FROM movies WHERE id = $1`, targetID).Scan(&target.ExternalIDs)
+	if err != nil {
+		return nil, err
+	}
+	if err := tx.Commit(); err != nil {
+		return nil, err
+	}
+	m.suggestions.clear()
+	return &target, nil
+}
+
+// externalIDClashes returns the sources of which both movies have an id, as
+// "source: target id / source id".
+func externalIDClashes(ctx context.Context, tx *sql.Tx, targetID, sourceID int64) ([]string, error) {
+	query := `SELECT t.source, t.external_id, s.external_id
+	FROM movie_external_ids t
+	JOIN movie_external_ids s ON s.source = t.source
+	WHERE t.movie_id = $1 AND s.movie_id = $2
+	ORDER BY t.source`
+	rows, err := tx.QueryContext(ctx, query, targetID, sourceID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var clashes []string
+	for rows.Next() {
+		var source, targetExt, sourceExt string
+		if err := rows.Scan(&source, &targetExt, &sourceExt); err != nil {
+			return nil, err
+		}
+		clashes = append(clashes, fmt.Sprintf("%v: %v / %v", source, targetExt, sourceExt))
+	}
+	return clashes, rows.Err()
+}
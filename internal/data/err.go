@@ -3,11 +3,20 @@ package data
 import (
 	"errors"
 	"fmt"
+	"strings"
+
+	"github.com/lib/pq"
 )
 
 // https://earthly.dev/blog/golang-errors/
 var (
 	ErrRecordNotFound = errors.New("record not found")
+	ErrEditConflict   = errors.New("unable to update the record due to an edit conflict, try again")
+	ErrDuplicate      = errors.New("record already exists")
+	ErrInvalidOrder   = errors.New("movie ids should contain every member movie exactly once")
+	ErrDuplicateGenre = errors.New("genre or alias already exists")
+	ErrGenreInUse     = errors.New("genre is still used by movies")
+	ErrInvalidCursor  = errors.New("invalid or tampered cursor")
 )
 
 type ErrDupEmail struct {
@@ -17,3 +26,52 @@ type ErrDupEmail struct {
 func (e *ErrDupEmail) Error() string {
 	return fmt.Sprintf("Duplicate Email %v", e.email)
 }
+
+// Unwrap makes ErrDupEmail an ErrDuplicate for errors.Is.
+func (e *ErrDupEmail) Unwrap() error {
+	return ErrDuplicate
+}
+
+// ErrExternalIDClash refuses to merge two movies which both have an id of the
+// same source, as a movie can only have one.
+type ErrExternalIDClash struct {
+	clashes []string
+}
+
+func (e *ErrExternalIDClash) Error() string {
+	return fmt.Sprintf("both movies have an external id of the same source (%v)", strings.Join(e.clashes, ", "))
+}
+
+// Unwrap makes ErrExternalIDClash an ErrEditConflict for errors.Is.
+func (e *ErrExternalIDClash) Unwrap() error {
+	return ErrEditConflict
+}
+
+// ErrTooManyGenres refuses to merge two movies whose genres together go over the
+// five allowed by genres_length_check, the merge must then name the genres kept.
+type ErrTooManyGenres struct {
+	Genres []string
+}
+
+func (e *ErrTooManyGenres) Error() string {
+	return fmt.Sprintf("the merged movie would have %d genres (%v), at most 5 are allowed", len(e.Genres), strings.Join(e.Genres, ", "))
+}
+
+// isUniqueViolation tells whether err is a PSQL unique_violation, of constraint
+// when it is not empty.
+func isUniqueViolation(err error, constraint string) bool {
+	var pqErr *pq.Error
+	if !errors.As(err, &pqErr) || pqErr.Code.Name() != "unique_violation" {
+		return false
+	}
+	return constraint == "" || pqErr.Constraint == constraint
+}
+
+// dbError turns the PSQL errors which are about the request rather than the
+// server into the sentinel errors above, other errors are returned as is.
+func dbError(err error) error {
+	if isUniqueViolation(err, "") {
+		return fmt.Errorf("%w: %v", ErrDuplicate, err.(*pq.Error).Constraint)
+	}
+	return err
+}
@@ -0,0 +1,124 @@
+package data
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+
+	"github.com/lib/pq"
+)
+
+// Rows fetched from the server side cursor at a time
+const exportFetchSize = 500
+
+// MovieExport is an export in progress, it is read like sql.Rows:
+//
+//	for export.Next() {
+//		movie := export.Movie()
+//	}
+//	err := export.Err()
+//
+// and must be closed.
+type MovieExport struct {
+	ctx   context.Context
+	tx    *sql.Tx
+	batch []*Movie
+	movie *Movie
+	done  bool
+	err   error
+}
+
+// Export opens a server side cursor over every movie matching the ListMovie
+// filters, in sort order, and fetches the first batch of rows so that a failing
+// query is reported here rather than halfway through the response. Rows are then
+// pulled by small batches, so memory use doesn't depend on the size of the table.
+// There is no timeout: the export lasts as long as ctx, which is expected to be
+// the request context so that an aborted download stops the query.
+func (m MovieModel) Export(ctx context.Context, lm ListMovie) (*MovieExport, error) {
+	tx, err := m.DB.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, err
+	}
+	e := &MovieExport{ctx: ctx, tx: tx}
+	where, args := lm.where()
+	qry := fmt.Sprintf(`DECLARE movies_export NO SCROLL CURSOR FOR
+	SELECT id, created_at, title, year, runtime, genres, version
+	FROM movies
+	%v
+	ORDER BY %v`, where, lm.Filters.orderBy())
+	if _, err := tx.ExecContext(ctx, qry, args...); err != nil {
+		e.Close()
+		return nil, err
+	}
+	if err := e.fetch(); err != nil {
+		e.Close()
+		return nil, err
+	}
+	return e, nil
+}
+
+// fetch reads the next batch of rows of the cursor.
+func (e *MovieExport) fetch() error {
+	rows, err := e.tx.QueryContext(e.ctx, fmt.Sprintf(`FETCH %d FROM movies_export`, exportFetchSize))
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+	e.batch = e.batch[:0]
+	for rows.Next() {
+		var movie Movie
+		err := rows.Scan(
+			&movie.ID,
+			&movie.CreatedAt,
+			&movie.Title,
+			&movie.Year,
+			&movie.Runtime,
+			pq.Array(&movie.Genres),
+			&movie.Version,
+		)
+		if err != nil {
+			return err
+		}
+		e.batch = append(e.batch, &movie)
+	}
+	if err := rows.Err(); err != nil {
+		return err
+	}
+	// A short batch is the last one
+	e.done = len(e.batch) < exportFetchSize
+	return nil
+}
+
+// Next moves to the next movie, it returns false at the end of the export or on
+// error, see Err.
+func (e *MovieExport) Next() bool {
+	if e.err != nil {
+		return false
+	}
+	if len(e.batch) == 0 {
+		if e.done {
+			return false
+		}
+		if e.err = e.fetch(); e.err != nil || len(e.batch) == 0 {
+			return false
+		}
+	}
+	e.movie, e.batch = e.batch[0], e.batch[1:]
+	return true
+}
+
+// Movie returns the current movie.
+func (e *MovieExport) Movie() *Movie {
+	return e.movie
+}
+
+// Err returns the error which stopped Next, if any.
+func (e *MovieExport) Err() error {
+	return e.err
+}
+
+// Close releases the cursor and its transaction.
+func (e *MovieExport) Close() error {
+	// Read only, hence rolling back is as good as committing
+	return e.tx.Rollback()
+}
@@ -0,0 +1,171 @@
+package data
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/lib/pq"
+)
+
+// externalIDsCol selects the external ids of the movie of the current row as a
+// JSON object, ready to be scanned into ExternalIDs.
+const externalIDsCol = `(SELECT json_object_agg(source, external_id)
+	FROM movie_external_ids WHERE movie_id = movies.id)`
+
+// ExternalIDs maps a source (imdb, tmdb...) to the id of the movie in that source.
+type ExternalIDs map[string]string
+
+func (e *ExternalIDs) Scan(src interface{}) error {
+	switch v := src.(type) {
+	case nil:
+		*e = nil
+		return nil
+	case []byte:
+		return json.Unmarshal(v, e)
+	case string:
+		return json.Unmarshal([]byte(v), e)
+	default:
+		return fmt.Errorf("unable to scan %T into ExternalIDs", src)
+	}
+}
+
+func (m MovieModel) GetByExternalID(source, externalID string) (*Movie, error) {
+	qry := `SELECT id, created_at, title, year, runtime, genres, version, ` + externalIDsCol + `
+	FROM movies
+	WHERE id = (SELECT movie_id FROM movie_external_ids WHERE source = $1 AND external_id = $2)`
+	var movie Movie
+	ctx, cancel := context.WithTimeout(context.Background(), timeout*time.Second)
+	defer cancel()
+	err := m.DB.QueryRowContext(ctx, qry, source, externalID).Scan(
+		&movie.ID,
+		&movie.CreatedAt,
+		&movie.Title,
+		&movie.Year,
+		&movie.Runtime,
+		pq.Array(&movie.Genres),
+		&movie.Version,
+		&movie.ExternalIDs,
+	)
+	if err != nil {
+		switch {
+		case errors.Is(err, sql.ErrNoRows):
+			return nil, fmt.Errorf("%w", ErrRecordNotFound)
+		default:
+			return nil, err
+		}
+	}
+	return &movie, nil
+}
+
+// UpsertByExternalID creates the movie known as externalID by source, or updates
+// it when it exists already. Sending the same movie twice has no further effect.
+// The returned status is one of ImportCreated, ImportUpdated or ImportUnchanged.
+func (m MovieModel) UpsertByExternalID(source, externalID string, movie *Movie) (string, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), timeout*time.Second)
+	defer cancel()
+	tx, err := m.DB.BeginTx(ctx, nil)
+	if err != nil {
+		return "", err
+	}
+	defer tx.Rollback()
+	status, err := upsertExternal(ctx, tx, source, externalID, movie)
+	if err != nil {
+		return "", err
+	}
+	err = tx.QueryRowContext(ctx, `SELECT `+externalIDsCol+` FROM movies WHERE id = $1`, movie.ID).Scan(&movie.ExternalIDs)
+	if err != nil {
+		return "", err
+	}
+	if err := tx.Commit(); err != nil {
+		return "", err
+	}
+	if status != ImportUnchanged {
+		m.suggestions.clear()
+	}
+	return status, nil
+}
+
+// upsertExternal creates or updates the movie known as externalID by source.
+// Updating a movie to the values it already has leaves its version alone.
+func upsertExternal(ctx context.Context, tx *sql.Tx, source, externalID string, movie *Movie) (string, error) {
+	query := `SELECT movie_id FROM movie_external_ids
+	WHERE source = $1 AND external_id = $2
+	FOR UPDATE`
+	err := tx.QueryRowContext(ctx, query, source, externalID).Scan(&movie.ID)
+	switch {
+	case errors.Is(err, sql.ErrNoRows):
+		query = `INSERT INTO movies (title, year, runtime, genres)
+		VALUES ($1, $2, $3, $4)
+		RETURNING id, created_at, version`
+		args := []interface{}{movie.Title, movie.Year, movie.Runtime, pq.Array(movie.Genres)}
+		if err := tx.QueryRowContext(ctx, query, args...).Scan(&movie.ID, &movie.CreatedAt, &movie.Version); err != nil {
+			return "", err
+		}
+		query = `INSERT INTO movie_external_ids (source, external_id, movie_id) VALUES ($1, $2, $3)`
+		if _, err := tx.ExecContext(ctx, query, source, externalID, movie.ID); err != nil {
+			return "", err
+		}
+		return ImportCreated, nil
+	case err != nil:
+		return "", err
+	}
+
+	query = `UPDATE movies
+	SET title = $1, year = $2, runtime = $3, genres = $4, version = version + 1
+	WHERE id = $5 AND (title, year, runtime, genres) IS DISTINCT FROM ($1, $2, $3, $4::text[])
+	RETURNING created_at, version`
+	args := []interface{}{movie.Title, movie.Year, movie.Runtime, pq.Array(movie.Genres), movie.ID}
+	err = tx.QueryRowContext(ctx, query, args...).Scan(&movie.CreatedAt, &movie.Version)
+	switch {
+	case errors.Is(err, sql.ErrNoRows):
+		query = `SELECT created_at, version FROM movies WHERE id = $1`
+		if err := tx.QueryRowContext(ctx, query, movie.ID).Scan(&movie.CreatedAt, &movie.Version); err != nil {
+			return "", err
+		}
+		return ImportUnchanged, nil
+	case err != nil:
+		return "", err
+	}
+	return ImportUpdated, nil
+}
+
+// UpsertBatchByExternalID upserts every movie under the external id at the same
+// index, in a single transaction. Like InsertBatch, a movie rejected by the
+// database fails on its own without aborting the rest of the batch.
+func (m MovieModel) UpsertBatchByExternalID(source string, externalIDs []string, movies []*Movie) ([]ImportResult, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), batchTimeout*time.Second)
+	defer cancel()
+	tx, err := m.DB.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+	results := make([]ImportResult, len(movies))
+	for i, movie := range movies {
+		if _, err := tx.ExecContext(ctx, `SAVEPOINT upsert_row`); err != nil {
+			return nil, err
+		}
+		status, err := upsertExternal(ctx, tx, source, externalIDs[i], movie)
+		if err != nil {
+			var pqErr *pq.Error
+			if !errors.As(err, &pqErr) {
+				return nil, err
+			}
+			results[i] = ImportResult{Status: ImportFailed, Err: err}
+			if _, err := tx.ExecContext(ctx, `ROLLBACK TO SAVEPOINT upsert_row`); err != nil {
+				return nil, err
+			}
+			continue
+		}
+		results[i] = ImportResult{Status: status}
+	}
+	if err := tx.Commit(); err != nil {
+		return nil, err
+	}
+	m.suggestions.clear()
+	return results, nil
+}
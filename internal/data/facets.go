@@ -0,0 +1,72 @@
+package data
+
+import (
+	"context"
+	"fmt"
+	"time"
+)
+
+type FacetCount struct {
+	Value string `json:"value"`
+	Count int    `json:"count"`
+}
+
+// facetQueries holds, per facet, the value and the ordering to aggregate on. They
+// all run against the WHERE clause of GetAll, so the counts describe the same
+// movies as the listing.
+var facetQueries = map[string]string{
+	"genres": `SELECT g, count(*)
+	FROM movies, unnest(movies.genres) AS g
+	%v
+	GROUP BY g
+	ORDER BY count(*) DESC, g ASC`,
+	"decade": `SELECT (year / 10 * 10)::text || 's', count(*)
+	FROM movies
+	%v
+	GROUP BY year / 10
+	ORDER BY year / 10 ASC`,
+	"runtime": `SELECT CASE b WHEN 0 THEN '0-89' WHEN 1 THEN '90-119' WHEN 2 THEN '120-149' ELSE '150+' END, count(*)
+	FROM (SELECT CASE
+			WHEN runtime < 90 THEN 0
+			WHEN runtime < 120 THEN 1
+			WHEN runtime < 150 THEN 2
+			ELSE 3 END AS b
+		FROM movies
+		%v) AS buckets
+	GROUP BY b
+	ORDER BY b ASC`,
+}
+
+// Facets counts the movies matching the ListMovie filters per value of each of the
+// requested facets (genres, decade, runtime).
+func (m MovieModel) Facets(lm ListMovie, facets []string) (map[string][]*FacetCount, error) {
+	out := make(map[string][]*FacetCount)
+	where, args := lm.where()
+	ctx, cancel := context.WithTimeout(context.Background(), timeout*time.Second)
+	defer cancel()
+	for _, facet := range facets {
+		query, ok := facetQueries[facet]
+		if !ok {
+			return nil, fmt.Errorf("unknown facet %v", facet)
+		}
+		rows, err := m.DB.QueryContext(ctx, fmt.Sprintf(query, where), args...)
+		if err != nil {
+			return nil, err
+		}
+		counts := []*FacetCount{}
+		for rows.Next() {
+			var fc FacetCount
+			if err := rows.Scan(&fc.Value, &fc.Count); err != nil {
+				rows.Close()
+				return nil, err
+			}
+			counts = append(counts, &fc)
+		}
+		rows.Close()
+		if err := rows.Err(); err != nil {
+			return nil, err
+		}
+		out[facet] = counts
+	}
+	return out, nil
+}
@@ -0,0 +1,69 @@
+package data
+
+import (
+	"strings"
+
+	"github.com/lib/pq"
+)
+
+// MovieFields are the fields a client can pick with fields=, they are named after
+// the JSON keys of Movie.
+var MovieFields = []string{"id", "title", "year", "runtime", "genres", "version", "external_ids"}
+
+// Fields selected when the client doesn't pick any
+var allMovieFields = []string{"id", "created_at", "title", "year", "runtime", "genres", "version", "external_ids"}
+
+// movieFields returns the fields to select, id is always part of them as well as
+// extra, e.g. the sort column a cursor is built from.
+func movieFields(fields []string, extra ...string) []string {
+	if len(fields) == 0 {
+		return allMovieFields
+	}
+	out := []string{"id"}
+	seen := map[string]bool{"id": true}
+	for _, f := range append(append([]string{}, fields...), extra...) {
+		if !seen[f] {
+			seen[f] = true
+			out = append(out, f)
+		}
+	}
+	return out
+}
+
+func movieColumns(fields []string) string {
+	cols := make([]string, len(fields))
+	for i, f := range fields {
+		if f == "external_ids" {
+			cols[i] = externalIDsCol
+		} else {
+			cols[i] = f
+		}
+	}
+	return strings.Join(cols, ", ")
+}
+
+// scanDest returns the destinations to scan the columns of fields into.
+func (movie *Movie) scanDest(fields []string) []interface{} {
+	dest := make([]interface{}, len(fields))
+	for i, f := range fields {
+		switch f {
+		case "id":
+			dest[i] = &movie.ID
+		case "created_at":
+			dest[i] = &movie.CreatedAt
+		case "title":
+			dest[i] = &movie.Title
+		case "year":
+			dest[i] = &movie.Year
+		case "runtime":
+			dest[i] = &movie.Runtime
+		case "genres":
+			dest[i] = pq.Array(&movie.Genres)
+		case "version":
+			dest[i] = &movie.Version
+		case "external_ids":
+			dest[i] = &movie.ExternalIDs
+		}
+	}
+	return dest
+}
@@ -10,22 +10,47 @@ type Filters struct {
 	Page     int    `form:"page" binding:"omitempty,min=1,max=10000"`
 	PageSize int    `form:"page_size" binding:"omitempty,min=1,max=100"`
 	Sort     string `form:"sort" binding:"omitempty,oneof=id title year runtime -id -title -year -runtime"`
+	// Keyset pagination, pagination=cursor asks for the first page and the
+	// next_cursor/prev_cursor of the metadata are used to move from there.
+	Cursor     string `form:"cursor" binding:"omitempty,max=1024"`
+	Pagination string `form:"pagination" binding:"omitempty,oneof=page cursor"`
 }
 
 // Define a new Metadata struct for holding the pagination metadata.
 type Metadata struct {
-	CurrentPage  int `json:"current_page,omitempty"`
-	PageSize     int `json:"page_size,omitempty"`
-	FirstPage    int `json:"first_page,omitempty"`
-	LastPage     int `json:"last_page,omitempty"`
-	TotalRecords int `json:"total_records,omitempty"`
+	CurrentPage  int    `json:"current_page,omitempty"`
+	PageSize     int    `json:"page_size,omitempty"`
+	FirstPage    int    `json:"first_page,omitempty"`
+	LastPage     int    `json:"last_page,omitempty"`
+	TotalRecords int    `json:"total_records,omitempty"`
+	NextCursor   string `json:"next_cursor,omitempty"`
+	PrevCursor   string `json:"prev_cursor,omitempty"`
 }
 
-func (f *Filters) sortCol() string {
-	if strings.HasPrefix(f.Sort, "-") {
-		return fmt.Sprintf("%v DESC", strings.TrimPrefix(f.Sort, "-"))
+// orderBy is the ORDER BY clause of the sort. Ties are broken by id in the same
+// direction, so that keyset pagination can seek past a (column, id) row.
+func (f *Filters) orderBy() string {
+	return f.order(f.sortDesc())
+}
+
+func (f *Filters) order(desc bool) string {
+	dir := "ASC"
+	if desc {
+		dir = "DESC"
 	}
-	return fmt.Sprintf("%v ASC", f.Sort)
+	return fmt.Sprintf("%[1]v %[2]v, id %[2]v", f.sortColumn(), dir)
+}
+
+func (f *Filters) cursorMode() bool {
+	return f.Cursor != "" || f.Pagination == "cursor"
+}
+
+func (f *Filters) sortColumn() string {
+	return strings.TrimPrefix(f.Sort, "-")
+}
+
+func (f *Filters) sortDesc() bool {
+	return strings.HasPrefix(f.Sort, "-")
 }
 
 func (f *Filters) limit() int {
@@ -0,0 +1,247 @@
+package data
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/lib/pq"
+)
+
+type GenreModel struct {
+	DB *sql.DB
+}
+
+func (m GenreModel) GetAll() ([]*Genre, error) {
+	query := `SELECT genres.id, genres.slug, genres.name,
+	COALESCE(array_agg(genre_aliases.alias ORDER BY genre_aliases.alias)
+		FILTER (WHERE genre_aliases.alias IS NOT NULL), '{}')
+	FROM genres
+	LEFT JOIN genre_aliases ON genre_aliases.genre_id = genres.id
+	GROUP BY genres.id
+	ORDER BY genres.slug`
+	ctx, cancel := context.WithTimeout(context.Background(), timeout*time.Second)
+	defer cancel()
+	rows, err := m.DB.QueryContext(ctx, query)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	genres := []*Genre{}
+	for rows.Next() {
+		var genre Genre
+		if err := rows.Scan(&genre.ID, &genre.Slug, &genre.Name, pq.Array(&genre.Aliases)); err != nil {
+			return nil, err
+		}
+		genres = append(genres, &genre)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return genres, nil
+}
+
+// Insert adds the genre along with its aliases. A slug or alias which is already
+// known, either as a slug or as an alias, is rejected with ErrDuplicateGenre.
+func (m GenreModel) Insert(genre *Genre) error {
+	ctx, cancel := context.WithTimeout(context.Background(), timeout*time.Second)
+	defer cancel()
+	tx, err := m.DB.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	query := `INSERT INTO genres (slug, name)
+	SELECT $1, $2 WHERE NOT EXISTS (SELECT 1 FROM genre_aliases WHERE alias = $1)
+	ON CONFLICT (slug) DO NOTHING
+	RETURNING id`
+	err = tx.QueryRowContext(ctx, query, genre.Slug, genre.Name).Scan(&genre.ID)
+	if err != nil {
+		switch {
+		case errors.Is(err, sql.ErrNoRows):
+			return fmt.Errorf("%w", ErrDuplicateGenre)
+		default:
+			return err
+		}
+	}
+	for _, alias := range genre.Aliases {
+		if err := addAlias(ctx, tx, genre.Slug, alias); err != nil {
+			return err
+		}
+	}
+	return tx.Commit()
+}
+
+func (m GenreModel) Update(genre *Genre) error {
+	query := `UPDATE genres SET name = $1 WHERE slug = $2 RETURNING id`
+	ctx, cancel := context.WithTimeout(context.Background(), timeout*time.Second)
+	defer cancel()
+	err := m.DB.QueryRowContext(ctx, query, genre.Name, genre.Slug).Scan(&genre.ID)
+	if err != nil {
+		switch {
+		case errors.Is(err, sql.ErrNoRows):
+			return fmt.Errorf("%w", ErrRecordNotFound)
+		default:
+			return err
+		}
+	}
+	return nil
+}
+
+// Delete removes a genre which no movie uses anymore, use Merge to get rid of a
+// genre which is still in use.
+func (m GenreModel) Delete(slug string) error {
+	query := `DELETE FROM genres
+	WHERE slug = $1 AND NOT EXISTS (SELECT 1 FROM movies WHERE $1 = ANY(genres))`
+	ctx, cancel := context.WithTimeout(context.Background(), timeout*time.Second)
+	defer cancel()
+	r, err := m.DB.ExecContext(ctx, query, slug)
+	if err != nil {
+		return err
+	}
+	rowsAffected, err := r.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if rowsAffected == 0 {
+		var exists bool
+		err := m.DB.QueryRowContext(ctx, `SELECT EXISTS (SELECT 1 FROM genres WHERE slug = $1)`, slug).Scan(&exists)
+		if err != nil {
+			return err
+		}
+		if exists {
+			return fmt.Errorf("%w", ErrGenreInUse)
+		}
+		return fmt.Errorf("%w", ErrRecordNotFound)
+	}
+	return nil
+}
+
+func (m GenreModel) AddAlias(slug, alias string) error {
+	ctx, cancel := context.WithTimeout(context.Background(), timeout*time.Second)
+	defer cancel()
+	tx, err := m.DB.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+	var exists bool
+	err = tx.QueryRowContext(ctx, `SELECT EXISTS (SELECT 1 FROM genres WHERE slug = $1)`, slug).Scan(&exists)
+	if err != nil {
+		return err
+	}
+	if !exists {
+		return fmt.Errorf("%w", ErrRecordNotFound)
+	}
+	if err := addAlias(ctx, tx, slug, alias); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+func addAlias(ctx context.Context, tx *sql.Tx, slug, alias string) error {
+	query := `INSERT INTO genre_aliases (alias, genre_id)
+	SELECT $1, genres.id FROM genres
+	WHERE genres.slug = $2 AND NOT EXISTS (SELECT 1 FROM genres WHERE slug = $1)
+	ON CONFLICT (alias) DO NOTHING`
+	r, err := tx.ExecContext(ctx, query, alias, slug)
+	if err != nil {
+		return err
+	}
+	rowsAffected, err := r.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if rowsAffected == 0 {
+		return fmt.Errorf("%w", ErrDuplicateGenre)
+	}
+	return nil
+}
+
+func (m GenreModel) RemoveAlias(slug, alias string) error {
+	query := `DELETE FROM genre_aliases
+	USING genres
+	WHERE genres.id = genre_aliases.genre_id AND genres.slug = $1 AND genre_aliases.alias = $2`
+	ctx, cancel := context.WithTimeout(context.Background(), timeout*time.Second)
+	defer cancel()
+	r, err := m.DB.ExecContext(ctx, query, slug, alias)
+	if err != nil {
+		return err
+	}
+	rowsAffected, err := r.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if rowsAffected == 0 {
+		return fmt.Errorf("%w", ErrRecordNotFound)
+	}
+	return nil
+}
+
+// Merge folds the genre from into the genre into: the aliases of from move over,
+// from itself becomes an alias of into and every movie using from is rewritten.
+func (m GenreModel) Merge(from, into string) error {
+	ctx, cancel := context.WithTimeout(context.Background(), timeout*time.Second)
+	defer cancel()
+	tx, err := m.DB.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	var fromID, intoID int64
+	query := `SELECT id FROM genres WHERE slug = $1 FOR UPDATE`
+	locks := []struct {
+		slug string
+		id   *int64
+	}{{from, &fromID}, {into, &intoID}}
+	// Rows are locked by ascending slug, so that merging a into b while b is merged
+	// into a can't deadlock
+	if into < from {
+		locks[0], locks[1] = locks[1], locks[0]
+	}
+	for _, g := range locks {
+		if err := tx.QueryRowContext(ctx, query, g.slug).Scan(g.id); err != nil {
+			switch {
+			case errors.Is(err, sql.ErrNoRows):
+				return fmt.Errorf("%w", ErrRecordNotFound)
+			default:
+				return err
+			}
+		}
+	}
+	if fromID == intoID {
+		return fmt.Errorf("%w", ErrDuplicateGenre)
+	}
+	_, err = tx.ExecContext(ctx, `UPDATE genre_aliases SET genre_id = $1 WHERE genre_id = $2`, intoID, fromID)
+	if err != nil {
+		return err
+	}
+	_, err = tx.ExecContext(ctx, `DELETE FROM genres WHERE id = $1`, fromID)
+	if err != nil {
+		return err
+	}
+	_, err = tx.ExecContext(ctx, `INSERT INTO genre_aliases (alias, genre_id) VALUES ($1, $2)`, from, intoID)
+	if err != nil {
+		return err
+	}
+	// Replace the slug and drop the duplicate which appears when a movie had both,
+	// keeping the original order.
+	query = `UPDATE movies
+	SET genres = ARRAY(
+		SELECT u.g FROM (
+			SELECT t.g, min(t.ord) AS ord
+			FROM unnest(array_replace(genres, $1, $2)) WITH ORDINALITY AS t(g, ord)
+			GROUP BY t.g
+		) AS u ORDER BY u.ord),
+	version = version + 1
+	WHERE $1 = ANY(genres)`
+	_, err = tx.ExecContext(ctx, query, from, into)
+	if err != nil {
+		return err
+	}
+	return tx.Commit()
+}
@@ -0,0 +1,83 @@
+package data
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"time"
+
+	"github.com/lib/pq"
+)
+
+const batchTimeout = 15
+
+const (
+	ImportCreated   = "created"
+	ImportUpdated   = "updated"
+	ImportUnchanged = "unchanged"
+	ImportSkipped   = "skipped"
+	ImportFailed    = "failed"
+)
+
+// ImportResult is the outcome for one movie of a batch. Err is only set for
+// ImportFailed.
+type ImportResult struct {
+	Status string
+	Err    error
+}
+
+// InsertBatch inserts the movies in a single transaction. A movie with the same
+// title (ignoring case) and year as an existing one is skipped, a movie rejected
+// by the database fails on its own without aborting the rest of the batch. With
+// dryRun the transaction is rolled back, so the results tell what would happen.
+func (m MovieModel) InsertBatch(movies []*Movie, dryRun bool) ([]ImportResult, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), batchTimeout*time.Second)
+	defer cancel()
+	tx, err := m.DB.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	query := `INSERT INTO movies (title, year, runtime, genres)
+	SELECT $1, $2, $3, $4
+	WHERE NOT EXISTS (SELECT 1 FROM movies WHERE lower(title) = lower($1) AND year = $2)
+	RETURNING id, created_at, version`
+	results := make([]ImportResult, len(movies))
+	created := false
+	for i, movie := range movies {
+		// The savepoint keeps the transaction usable when a row is rejected
+		if _, err := tx.ExecContext(ctx, `SAVEPOINT import_row`); err != nil {
+			return nil, err
+		}
+		args := []interface{}{movie.Title, movie.Year, movie.Runtime, pq.Array(movie.Genres)}
+		err := tx.QueryRowContext(ctx, query, args...).Scan(&movie.ID, &movie.CreatedAt, &movie.Version)
+		switch {
+		case err == nil:
+			results[i] = ImportResult{Status: ImportCreated}
+			created = true
+		case errors.Is(err, sql.ErrNoRows):
+			results[i] = ImportResult{Status: ImportSkipped}
+		default:
+			var pqErr *pq.Error
+			if !errors.As(err, &pqErr) {
+				// Not about the row itself (timeout, lost connection...)
+				return nil, err
+			}
+			results[i] = ImportResult{Status: ImportFailed, Err: err}
+			if _, err := tx.ExecContext(ctx, `ROLLBACK TO SAVEPOINT import_row`); err != nil {
+				return nil, err
+			}
+		}
+	}
+	if dryRun {
+		return results, nil
+	}
+	if err := tx.Commit(); err != nil {
+		return nil, err
+	}
+	if created {
+		m.suggestions.clear()
+	}
+	return results, nil
+}
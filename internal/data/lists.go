@@ -0,0 +1,281 @@
+package data
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/lib/pq"
+)
+
+type ListModel struct {
+	DB *sql.DB
+}
+
+// builtInLists are the lists every user has, by kind.
+var builtInLists = []struct{ kind, name string }{
+	{ListToWatch, "To watch"},
+	{ListWatched, "Watched"},
+}
+
+// insertBuiltInLists gives the user the lists of builtInLists, those the user
+// already has are left alone. It is part of UserModel.Register.
+func insertBuiltInLists(ctx context.Context, tx *sql.Tx, userID int64) error {
+	query := `INSERT INTO lists (user_id, name, kind)
+	VALUES ($1, $2, $3)
+	ON CONFLICT DO NOTHING`
+	for _, l := range builtInLists {
+		if _, err := tx.ExecContext(ctx, query, userID, l.name, l.kind); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Insert creates a custom list, the built-in ones come with UserModel.Register.
+func (m ListModel) Insert(list *List) error {
+	list.Kind = ListCustom
+	query := `INSERT INTO lists (user_id, name, public)
+	VALUES ($1, $2, $3)
+	RETURNING id, created_at, version`
+	args := []interface{}{list.UserID, list.Name, list.Public}
+	ctx, cancel := context.WithTimeout(context.Background(), timeout*time.Second)
+	defer cancel()
+	// A user can't have two lists of the same name
+	err := m.DB.QueryRowContext(ctx, query, args...).Scan(&list.ID, &list.CreatedAt, &list.Version)
+	return dbError(err)
+}
+
+func (m ListModel) Get(id int64) (*List, error) {
+	if id < 1 {
+		return nil, fmt.Errorf("%w", ErrRecordNotFound)
+	}
+	return m.get(`id = $1`, id)
+}
+
+// GetBuiltIn returns the list of kind ListToWatch or ListWatched of the user.
+func (m ListModel) GetBuiltIn(userID int64, kind string) (*List, error) {
+	return m.get(`user_id = $1 AND kind = $2`, userID, kind)
+}
+
+func (m ListModel) get(where string, args ...interface{}) (*List, error) {
+	query := `SELECT id, created_at, user_id, name, kind, public, version
+	FROM lists
+	WHERE ` + where
+	var list List
+	ctx, cancel := context.WithTimeout(context.Background(), timeout*time.Second)
+	defer cancel()
+	err := m.DB.QueryRowContext(ctx, query, args...).Scan(
+		&list.ID,
+		&list.CreatedAt,
+		&list.UserID,
+		&list.Name,
+		&list.Kind,
+		&list.Public,
+		&list.Version,
+	)
+	if err != nil {
+		switch {
+		case errors.Is(err, sql.ErrNoRows):
+			return nil, fmt.Errorf("%w", ErrRecordNotFound)
+		default:
+			return nil, err
+		}
+	}
+	return &list, nil
+}
+
+func (m ListModel) GetAllForUser(userID int64) ([]*List, error) {
+	// Built-in lists first
+	query := `SELECT id, created_at, user_id, name, kind, public, version
+	FROM lists
+	WHERE user_id = $1
+	ORDER BY kind = 'custom', id ASC`
+	ctx, cancel := context.WithTimeout(context.Background(), timeout*time.Second)
+	defer cancel()
+	rows, err := m.DB.QueryContext(ctx, query, userID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	lists := []*List{}
+	for rows.Next() {
+		var list List
+		err := rows.Scan(
+			&list.ID,
+			&list.CreatedAt,
+			&list.UserID,
+			&list.Name,
+			&list.Kind,
+			&list.Public,
+			&list.Version,
+		)
+		if err != nil {
+			return nil, err
+		}
+		lists = append(lists, &list)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return lists, nil
+}
+
+func (m ListModel) Update(list *List) error {
+	// Same optimistic locking as MovieModel.Update
+	query := `UPDATE lists
+	SET name = $1, public = $2, version = version + 1
+	WHERE id = $3 AND version = $4
+	RETURNING version`
+	args := []interface{}{list.Name, list.Public, list.ID, list.Version}
+	ctx, cancel := context.WithTimeout(context.Background(), timeout*time.Second)
+	defer cancel()
+	err := m.DB.QueryRowContext(ctx, query, args...).Scan(&list.Version)
+	if errors.Is(err, sql.ErrNoRows) {
+		return fmt.Errorf("%w", ErrEditConflict)
+	}
+	return dbError(err)
+}
+
+func (m ListModel) Delete(id int64) error {
+	if id < 1 {
+		return fmt.Errorf("%w", ErrRecordNotFound)
+	}
+	query := `DELETE FROM lists WHERE id = $1`
+	ctx, cancel := context.WithTimeout(context.Background(), timeout*time.Second)
+	defer cancel()
+	r, err := m.DB.ExecContext(ctx, query, id)
+	if err != nil {
+		return err
+	}
+	rowsAffected, err := r.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if rowsAffected == 0 {
+		return fmt.Errorf("%w", ErrRecordNotFound)
+	}
+	return nil
+}
+
+// AddMovie appends the movie at the end of the list. Adding a movie which is
+// already part of the list is a no-op.
+func (m ListModel) AddMovie(listID, movieID int64) error {
+	query := `INSERT INTO lists_movies (list_id, movie_id, position)
+	SELECT $1, $2, COALESCE(MAX(position), 0) + 1 FROM lists_movies WHERE list_id = $1
+	ON CONFLICT (list_id, movie_id) DO NOTHING`
+	ctx, cancel := context.WithTimeout(context.Background(), timeout*time.Second)
+	defer cancel()
+	_, err := m.DB.ExecContext(ctx, query, listID, movieID)
+	return err
+}
+
+func (m ListModel) RemoveMovie(listID, movieID int64) error {
+	query := `DELETE FROM lists_movies WHERE list_id = $1 AND movie_id = $2`
+	ctx, cancel := context.WithTimeout(context.Background(), timeout*time.Second)
+	defer cancel()
+	r, err := m.DB.ExecContext(ctx, query, listID, movieID)
+	if err != nil {
+		return err
+	}
+	rowsAffected, err := r.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if rowsAffected == 0 {
+		return fmt.Errorf("%w", ErrRecordNotFound)
+	}
+	return nil
+}
+
+// Reorder sets the position of every movie of the list to its index in movieIDs.
+// movieIDs must hold each movie of the list exactly once.
+func (m ListModel) Reorder(listID int64, movieIDs []int64) error {
+	return reorderMembers(m.DB, "lists_movies", "list_id", listID, movieIDs)
+}
+
+// reorderMembers rewrites the position column of an ordered membership table
+// (lists_movies, collections_movies) so that it follows movieIDs.
+func reorderMembers(db *sql.DB, table, keyCol string, id int64, movieIDs []int64) error {
+	ctx, cancel := context.WithTimeout(context.Background(), timeout*time.Second)
+	defer cancel()
+	tx, err := db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	var total int
+	// Lock the member rows so that a concurrent add/remove can't sneak in
+	// between the count and the update.
+	err = tx.QueryRowContext(ctx, fmt.Sprintf(`SELECT count(*) FROM (
+		SELECT 1 FROM %[1]v WHERE %[2]v = $1 FOR UPDATE) AS m`, table, keyCol), id).Scan(&total)
+	if err != nil {
+		return err
+	}
+	if total != len(movieIDs) {
+		return fmt.Errorf("%w", ErrInvalidOrder)
+	}
+	query := fmt.Sprintf(`UPDATE %[1]v
+	SET position = t.ord
+	FROM unnest($2::bigint[]) WITH ORDINALITY AS t(movie_id, ord)
+	WHERE %[1]v.%[2]v = $1 AND %[1]v.movie_id = t.movie_id`, table, keyCol)
+	r, err := tx.ExecContext(ctx, query, id, pq.Array(movieIDs))
+	if err != nil {
+		return err
+	}
+	rowsAffected, err := r.RowsAffected()
+	if err != nil {
+		return err
+	}
+	// Duplicate or unknown ids leave some rows untouched
+	if rowsAffected != int64(total) {
+		return fmt.Errorf("%w", ErrInvalidOrder)
+	}
+	return tx.Commit()
+}
+
+// GetMovies returns the movies of a list in list order, paginated the same way as
+// MovieModel.GetAll.
+func (m ListModel) GetMovies(listID int64, filters Filters) ([]*Movie, *Metadata, error) {
+	movies := []*Movie{}
+	tr := 0
+	query := `SELECT count(*) OVER(), movies.id, movies.created_at, movies.title, movies.year,
+	movies.runtime, movies.genres, movies.version
+	FROM movies
+	INNER JOIN lists_movies ON lists_movies.movie_id = movies.id
+	WHERE lists_movies.list_id = $1
+	ORDER BY lists_movies.position ASC, movies.id ASC
+	LIMIT $2 OFFSET $3`
+	ctx, cancel := context.WithTimeout(context.Background(), timeout*time.Second)
+	defer cancel()
+	rows, err := m.DB.QueryContext(ctx, query, listID, filters.limit(), filters.offset())
+	if err != nil {
+		return nil, nil, err
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var movie Movie
+		err := rows.Scan(
+			&tr,
+			&movie.ID,
+			&movie.CreatedAt,
+			&movie.Title,
+			&movie.Year,
+			&movie.Runtime,
+			pq.Array(&movie.Genres),
+			&movie.Version,
+		)
+		if err != nil {
+			return nil, nil, err
+		}
+		movies = append(movies, &movie)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, nil, err
+	}
+	metadata := calculateMetadata(tr, filters.Page, filters.PageSize)
+	return movies, &metadata, nil
+}
@@ -1,6 +1,7 @@
 package data
 
 import (
+	"context"
 	"database/sql"
 	"time"
 )
@@ -12,18 +13,46 @@ type Movie struct {
 	Title     string    `json:"title" binding:"required,min=1,max=255"`
 	Year      int32     `json:"year" binding:"required,yearrange"`
 	Runtime   Runtime   `json:"runtime" binding:"required"`
-	Genres    []string  `json:"genres" binding:"required,unique"`
+	Genres    []string  `json:"genres" binding:"required,genre"`
+	// Read only, managed through the by-external endpoints
+	ExternalIDs ExternalIDs `json:"external_ids,omitempty"`
 }
 
 type ListMovie struct {
-	Title  string   `form:"title" binding:"omitempty,min=2,max=255"`
-	Genres []string `form:"genres" binding:"omitempty,genre"`
+	Title      string   `form:"title" binding:"omitempty,min=2,max=255"`
+	Genres     []string `form:"genres" binding:"omitempty,csvgenre"`
+	GenreMatch string   `form:"genre_match" binding:"omitempty,oneof=any all"`
+	Collection int64    `form:"collection" binding:"omitempty,min=1"`
+	YearMin    int32    `form:"year_min" binding:"omitempty,yearrange"`
+	YearMax    int32    `form:"year_max" binding:"omitempty,yearrange,gtefield=YearMin"`
+	RuntimeMin Runtime  `form:"runtime_min" binding:"omitempty,runtimerange"`
+	RuntimeMax Runtime  `form:"runtime_max" binding:"omitempty,runtimerange,gtefield=RuntimeMin"`
+	Facets     []string `form:"facets" binding:"omitempty,csvoneof=genres decade runtime"`
+	Fields     []string `form:"fields" binding:"omitempty,csvoneof=id title year runtime genres version external_ids"`
 	Filters
 }
+
+// SearchMovie is the input of the ranked search, it accepts every ListMovie filter
+// on top of the search text.
+type SearchMovie struct {
+	Query    string `form:"q" binding:"required,min=1,max=255"`
+	Language string `form:"lang" binding:"omitempty,oneof=simple english french german spanish italian portuguese dutch"`
+	ListMovie
+}
+
+// SearchResult is a movie along with how well it matched the search. Highlight
+// is the title as escaped HTML, the matching words within <mark>.
+type SearchResult struct {
+	Movie
+	Score     float64 `json:"score"`
+	Highlight string  `json:"highlight"`
+}
+
 type IUser interface {
 	Update(*User) error
 	GetByEmail(string) (*User, error)
 	Insert(*User) error
+	Register(user *User, permissions ...string) error
 	GetForToken(tokenScope, tokenPlaintext string) (*User, error)
 }
 
@@ -36,17 +65,68 @@ type IPermission interface {
 	GetAllForUser(userID int64) (Permissions, error)
 	AddForUser(userID int64, codes ...string) error
 }
+type IList interface {
+	Insert(*List) error
+	Get(int64) (*List, error)
+	GetBuiltIn(userID int64, kind string) (*List, error)
+	GetAllForUser(int64) ([]*List, error)
+	Update(*List) error
+	Delete(int64) error
+	AddMovie(listID, movieID int64) error
+	RemoveMovie(listID, movieID int64) error
+	Reorder(listID int64, movieIDs []int64) error
+	GetMovies(int64, Filters) ([]*Movie, *Metadata, error)
+}
+type ICollection interface {
+	Insert(*Collection) error
+	Get(int64) (*Collection, error)
+	GetAll() ([]*Collection, error)
+	GetAllForMovie(int64) ([]*Collection, error)
+	Update(*Collection) error
+	Delete(int64) error
+	AddMovie(collectionID, movieID int64) error
+	RemoveMovie(collectionID, movieID int64) error
+	Reorder(collectionID int64, movieIDs []int64) error
+	GetMovies(int64) ([]*Movie, error)
+}
+type IGenre interface {
+	GetAll() ([]*Genre, error)
+	Insert(*Genre) error
+	Update(*Genre) error
+	Delete(slug string) error
+	AddAlias(slug, alias string) error
+	RemoveAlias(slug, alias string) error
+	Merge(from, into string) error
+}
+type IStats interface {
+	Get(days int) (*Stats, error)
+}
 type Models struct {
 	Movies interface {
 		Insert(movie *Movie) error
-		Get(id int64) (*Movie, error)
+		Get(id int64, fields ...string) (*Movie, error)
 		Update(movie *Movie) error
 		Delete(id int64) error
-		GetAll(string, []string, Filters) ([]*Movie, *Metadata, error)
+		GetAll(ListMovie) ([]*Movie, *Metadata, error)
+		Search(SearchMovie) ([]*SearchResult, *Metadata, error)
+		Autocomplete(prefix string, limit int) ([]*Suggestion, error)
+		Facets(ListMovie, []string) (map[string][]*FacetCount, error)
+		InsertBatch(movies []*Movie, dryRun bool) ([]ImportResult, error)
+		Export(ctx context.Context, lm ListMovie) (*MovieExport, error)
+		GetByExternalID(source, externalID string) (*Movie, error)
+		UpsertByExternalID(source, externalID string, movie *Movie) (string, error)
+		FindDuplicates(threshold float64, yearTolerance, runtimeTolerance int, filters Filters) ([]*DuplicateCandidate, *Metadata, error)
+		Merge(targetID, sourceID int64, genres []string) (*Movie, error)
+		Batch(ops []*BatchOp, atomic bool) error
+		GetMany(ids []int64) ([]*Movie, []int64, error)
 	}
 	User       IUser
 	Token      IToken
 	Permission IPermission
+	List       IList
+	Collection ICollection
+	Genre      IGenre
+	Stats      IStats
 }
 
 type User struct {
@@ -71,12 +151,52 @@ type Token struct {
 	Scope     string    `json:"-"`
 }
 
-func NewModel(db *sql.DB) Models {
+// Kinds of lists. Every user has one list of each built-in kind, ListToWatch and
+// ListWatched, which can't be renamed nor deleted.
+const (
+	ListCustom  = "custom"
+	ListToWatch = "to_watch"
+	ListWatched = "watched"
+)
+
+type List struct {
+	ID        int64     `json:"id"`
+	CreatedAt time.Time `json:"created_at"`
+	UserID    int64     `json:"user_id"`
+	Name      string    `json:"name"`
+	Kind      string    `json:"kind"`
+	Public    bool      `json:"public"`
+	Version   int32     `json:"version"`
+}
+
+type Collection struct {
+	ID          int64     `json:"id"`
+	CreatedAt   time.Time `json:"-"`
+	Name        string    `json:"name"`
+	Description string    `json:"description"`
+	Version     int32     `json:"version"`
+}
+
+type Genre struct {
+	ID      int64    `json:"-"`
+	Slug    string   `json:"slug"`
+	Name    string   `json:"name"`
+	Aliases []string `json:"aliases"`
+}
+
+// NewModel wires the models to the database. cursorKey signs the pagination
+// cursors handed out by MovieModel.GetAll and statsTTL is how long statistics are
+// cached for.
+func NewModel(db *sql.DB, cursorKey []byte, statsTTL time.Duration) Models {
 	return Models{
-		Movies:     MovieModel{DB: db},
+		Movies:     MovieModel{DB: db, CursorKey: cursorKey, suggestions: newSuggestionCache()},
 		User:       UserModel{DB: db},
 		Token:      TokenModel{DB: db},
 		Permission: PermissionModel{DB: db},
+		List:       ListModel{DB: db},
+		Collection: CollectionModel{DB: db},
+		Genre:      GenreModel{DB: db},
+		Stats:      NewStatsModel(db, statsTTL),
 	}
 }
 
@@ -14,7 +14,9 @@ import (
 const timeout = 3
 
 type MovieModel struct {
-	DB *sql.DB
+	DB          *sql.DB
+	CursorKey   []byte
+	suggestions *suggestionCache
 }
 
 func (m MovieModel) Insert(movie *Movie) error {
@@ -24,12 +26,19 @@ func (m MovieModel) Insert(movie *Movie) error {
 	args := []interface{}{movie.Title, movie.Year, movie.Runtime, pq.Array(movie.Genres)}
 	ctx, cancel := context.WithTimeout(context.Background(), timeout*time.Second)
 	defer cancel()
-	return m.DB.QueryRowContext(ctx, query, args...).Scan(&movie.ID, &movie.CreatedAt, &movie.Version)
+	err := m.DB.QueryRowContext(ctx, query, args...).Scan(&movie.ID, &movie.CreatedAt, &movie.Version)
+	if err != nil {
+		return dbError(err)
+	}
+	m.suggestions.clear()
+	return nil
 }
 
-func (m MovieModel) Get(id int64) (*Movie, error) {
+// Get returns the movie with the given id. When fields are given only those
+// columns are read, the others are left to their zero value.
+func (m MovieModel) Get(id int64, fields ...string) (*Movie, error) {
 	if id < 1 {
-		return nil, fmt.Errorf("record not found")
+		return nil, fmt.Errorf("%w", ErrRecordNotFound)
 	}
 
 	// To mimic timeout at DB
@@ -38,25 +47,18 @@ func (m MovieModel) Get(id int64) (*Movie, error) {
 	// FROM movies
 	// WHERE id = $1`
 
-	qry := `SELECT id, created_at, title, year, runtime, genres, version
+	fields = movieFields(fields)
+	qry := `SELECT ` + movieColumns(fields) + `
 	FROM movies
 	WHERE id = $1`
 	var movie Movie
 	ctx, cancel := context.WithTimeout(context.Background(), timeout*time.Second)
 	defer cancel()
-	err := m.DB.QueryRowContext(ctx, qry, id).Scan(
-		// &[]byte{},
-		&movie.ID,
-		&movie.CreatedAt,
-		&movie.Title,
-		&movie.Year,
-		&movie.Runtime,
-		pq.Array(&movie.Genres),
-		&movie.Version)
+	err := m.DB.QueryRowContext(ctx, qry, id).Scan(movie.scanDest(fields)...)
 	if err != nil {
 		switch {
 		case errors.Is(err, sql.ErrNoRows):
-			return nil, fmt.Errorf("record not found")
+			return nil, fmt.Errorf("%w", ErrRecordNotFound)
 		default:
 			return nil, err
 		}
@@ -81,11 +83,22 @@ func (m MovieModel) Update(movie *Movie) error {
 	}
 	ctx, cancel := context.WithTimeout(context.Background(), timeout*time.Second)
 	defer cancel()
-	return m.DB.QueryRowContext(ctx, query, args...).Scan(&movie.Version)
+	err := m.DB.QueryRowContext(ctx, query, args...).Scan(&movie.Version)
+	if err != nil {
+		switch {
+		// The movie was deleted or its version moved on since it was read
+		case errors.Is(err, sql.ErrNoRows):
+			return fmt.Errorf("%w", ErrEditConflict)
+		default:
+			return dbError(err)
+		}
+	}
+	m.suggestions.clear()
+	return nil
 }
 func (m MovieModel) Delete(id int64) error {
 	if id < 1 {
-		return fmt.Errorf("record not found")
+		return fmt.Errorf("%w", ErrRecordNotFound)
 	}
 	query := `DELETE FROM movies WHERE id = $1`
 	ctx, cancel := context.WithTimeout(context.Background(), timeout*time.Second)
@@ -99,35 +112,29 @@ func (m MovieModel) Delete(id int64) error {
 		return err
 	}
 	if rowsAffected == 0 {
-		return fmt.Errorf("record not found")
+		return fmt.Errorf("%w", ErrRecordNotFound)
 	}
+	m.suggestions.clear()
 	return nil
 }
 
-func (m MovieModel) GetAll(title string, genres []string, filters Filters) ([]*Movie, *Metadata, error) {
-	movies := []*Movie{}
-	tr := 0
-	qry := fmt.Sprintf(`SELECT count(*) OVER(), id, created_at, title, year, runtime, genres, version
+// GetMany returns the movies with the given ids in the order of ids, repeated ids
+// are only returned once. Ids matching no movie are returned in missing.
+func (m MovieModel) GetMany(ids []int64) ([]*Movie, []int64, error) {
+	qry := `SELECT id, created_at, title, year, runtime, genres, version, ` + externalIDsCol + `
 	FROM movies
-	WHERE (to_tsvector('simple', title) @@ plainto_tsquery('simple', $1) OR $1 = '')
-	AND (genres @> $2 OR $2 = '{}')
-	ORDER BY %v, id ASC
-	LIMIT $3 OFFSET $4`, filters.sortCol())
-	// qry := `SELECT id, created_at, title, year, runtime, genres, version FROM movies ORDER BY id`
-	// log.Println(qry)
+	WHERE id = ANY($1)`
 	ctx, cancel := context.WithTimeout(context.Background(), timeout*time.Second)
 	defer cancel()
-	args := []interface{}{title, pq.Array(genres), filters.limit(), filters.offset()}
-	log.Println(args)
-	rows, err := m.DB.QueryContext(ctx, qry, args...)
+	rows, err := m.DB.QueryContext(ctx, qry, pq.Array(ids))
 	if err != nil {
 		return nil, nil, err
 	}
 	defer rows.Close()
+	found := map[int64]*Movie{}
 	for rows.Next() {
 		var movie Movie
 		err := rows.Scan(
-			&tr,
 			&movie.ID,
 			&movie.CreatedAt,
 			&movie.Title,
@@ -135,10 +142,91 @@ func (m MovieModel) GetAll(title string, genres []string, filters Filters) ([]*M
 			&movie.Runtime,
 			pq.Array(&movie.Genres),
 			&movie.Version,
+			&movie.ExternalIDs,
 		)
 		if err != nil {
 			return nil, nil, err
 		}
+		found[movie.ID] = &movie
+	}
+	if err := rows.Err(); err != nil {
+		return nil, nil, err
+	}
+	movies := []*Movie{}
+	missing := []int64{}
+	seen := map[int64]bool{}
+	for _, id := range ids {
+		if seen[id] {
+			continue
+		}
+		seen[id] = true
+		if movie, ok := found[id]; ok {
+			movies = append(movies, movie)
+		} else {
+			missing = append(missing, id)
+		}
+	}
+	return movies, missing, nil
+}
+
+// where builds the WHERE clause matching the ListMovie filters. Placeholders start
+// at $1, callers append their own arguments after the returned ones.
+func (lm ListMovie) where() (string, []interface{}) {
+	// Both operators are backed by the GIN index on genres
+	genreOp := "@>"
+	if lm.GenreMatch == "any" {
+		genreOp = "&&"
+	}
+	clause := fmt.Sprintf(`WHERE (to_tsvector('simple', title) @@ plainto_tsquery('simple', $1) OR $1 = '')
+	AND (genres %v $2 OR $2 = '{}')
+	AND (id IN (SELECT movie_id FROM collections_movies WHERE collection_id = $3) OR $3 = 0)
+	AND (year >= $4 OR $4 = 0)
+	AND (year <= $5 OR $5 = 0)
+	AND (runtime >= $6 OR $6 = 0)
+	AND (runtime <= $7 OR $7 = 0)`, genreOp)
+	args := []interface{}{
+		lm.Title,
+		pq.Array(lm.Genres),
+		lm.Collection,
+		lm.YearMin,
+		lm.YearMax,
+		lm.RuntimeMin,
+		lm.RuntimeMax,
+	}
+	return clause, args
+}
+
+func (m MovieModel) GetAll(lm ListMovie) ([]*Movie, *Metadata, error) {
+	if lm.Filters.cursorMode() {
+		return m.getAllByCursor(lm)
+	}
+	movies := []*Movie{}
+	tr := 0
+	filters := lm.Filters
+	where, args := lm.where()
+	fields := movieFields(lm.Fields)
+	qry := fmt.Sprintf(`SELECT count(*) OVER(), %v
+	FROM movies
+	%v
+	ORDER BY %v
+	LIMIT $%d OFFSET $%d`, movieColumns(fields), where, filters.orderBy(), len(args)+1, len(args)+2)
+	// qry := `SELECT id, created_at, title, year, runtime, genres, version FROM movies ORDER BY id`
+	// log.Println(qry)
+	ctx, cancel := context.WithTimeout(context.Background(), timeout*time.Second)
+	defer cancel()
+	args = append(args, filters.limit(), filters.offset())
+	log.Println(args)
+	rows, err := m.DB.QueryContext(ctx, qry, args...)
+	if err != nil {
+		return nil, nil, err
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var movie Movie
+		err := rows.Scan(append([]interface{}{&tr}, movie.scanDest(fields)...)...)
+		if err != nil {
+			return nil, nil, err
+		}
 		movies = append(movies, &movie)
 	}
 	if err := rows.Err(); err != nil {
@@ -148,12 +236,102 @@ func (m MovieModel) GetAll(title string, genres []string, filters Filters) ([]*M
 	return movies, &metadata, nil
 }
 
+// getAllByCursor is the keyset flavour of GetAll. Rather than counting and skipping
+// rows it seeks past the (sort column, id) of the cursor, so deep pages cost the
+// same as the first one and rows inserted meanwhile don't shift the pages.
+func (m MovieModel) getAllByCursor(lm ListMovie) ([]*Movie, *Metadata, error) {
+	filters := lm.Filters
+	where, args := lm.where()
+	col := filters.sortColumn()
+	order := filters.orderBy()
+	digest := lm.filterDigest()
+	var c *cursor
+	if filters.Cursor != "" {
+		var err error
+		c, err = decodeCursor(filters.Cursor, m.CursorKey)
+		if err != nil {
+			return nil, nil, err
+		}
+		// A cursor only makes sense for the sort and filters it was created with
+		if c.Sort != filters.Sort || c.Filters != digest {
+			return nil, nil, fmt.Errorf("%w", ErrInvalidCursor)
+		}
+		v, err := c.value()
+		if err != nil {
+			return nil, nil, err
+		}
+		// Rows come after the cursor in the order of the sort, going backwards
+		// they come before it and are flipped once read
+		desc := filters.sortDesc()
+		if c.Direction == cursorPrev {
+			desc = !desc
+			order = filters.order(desc)
+		}
+		op := ">"
+		if desc {
+			op = "<"
+		}
+		where += fmt.Sprintf("\n\tAND (%v, id) %v ($%d, $%d)", col, op, len(args)+1, len(args)+2)
+		args = append(args, v, c.ID)
+	}
+	// The sort column is needed to build the cursors
+	fields := movieFields(lm.Fields, col)
+	qry := fmt.Sprintf(`SELECT %v
+	FROM movies
+	%v
+	ORDER BY %v
+	LIMIT $%d`, movieColumns(fields), where, order, len(args)+1)
+	// One extra row tells whether there is a page after this one
+	args = append(args, filters.limit()+1)
+	ctx, cancel := context.WithTimeout(context.Background(), timeout*time.Second)
+	defer cancel()
+	rows, err := m.DB.QueryContext(ctx, qry, args...)
+	if err != nil {
+		return nil, nil, err
+	}
+	defer rows.Close()
+	movies := []*Movie{}
+	for rows.Next() {
+		var movie Movie
+		err := rows.Scan(movie.scanDest(fields)...)
+		if err != nil {
+			return nil, nil, err
+		}
+		movies = append(movies, &movie)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, nil, err
+	}
+	hasMore := len(movies) > filters.limit()
+	if hasMore {
+		movies = movies[:filters.limit()]
+	}
+	backwards := c != nil && c.Direction == cursorPrev
+	if backwards {
+		for i, j := 0, len(movies)-1; i < j; i, j = i+1, j-1 {
+			movies[i], movies[j] = movies[j], movies[i]
+		}
+	}
+	metadata := Metadata{PageSize: filters.PageSize}
+	if len(movies) > 0 {
+		first, last := movies[0], movies[len(movies)-1]
+		// Going backwards we came from the next page, so it always exists
+		if backwards || hasMore {
+			metadata.NextCursor = newCursor(filters.Sort, digest, last, cursorNext).encode(m.CursorKey)
+		}
+		if (backwards && hasMore) || (!backwards && c != nil) {
+			metadata.PrevCursor = newCursor(filters.Sort, digest, first, cursorPrev).encode(m.CursorKey)
+		}
+	}
+	return movies, &metadata, nil
+}
+
 type MockMovieModel struct{}
 
 func (m MockMovieModel) Insert(movie *Movie) error {
 	return nil
 }
-func (m MockMovieModel) Get(id int64) (*Movie, error) {
+func (m MockMovieModel) Get(id int64, fields ...string) (*Movie, error) {
 	return nil, nil
 }
 func (m MockMovieModel) Update(movie *Movie) error {
@@ -162,6 +340,39 @@ func (m MockMovieModel) Update(movie *Movie) error {
 func (m MockMovieModel) Delete(id int64) error {
 	return nil
 }
-func (m MockMovieModel) GetAll(title string, genres []string, filters Filters) ([]*Movie, *Metadata, error) {
+func (m MockMovieModel) GetAll(lm ListMovie) ([]*Movie, *Metadata, error) {
+	return nil, nil, nil
+}
+func (m MockMovieModel) Search(sm SearchMovie) ([]*SearchResult, *Metadata, error) {
+	return nil, nil, nil
+}
+func (m MockMovieModel) Autocomplete(prefix string, limit int) ([]*Suggestion, error) {
+	return nil, nil
+}
+func (m MockMovieModel) Facets(lm ListMovie, facets []string) (map[string][]*FacetCount, error) {
+	return nil, nil
+}
+func (m MockMovieModel) InsertBatch(movies []*Movie, dryRun bool) ([]ImportResult, error) {
+	return nil, nil
+}
+func (m MockMovieModel) Export(ctx context.Context, lm ListMovie) (*MovieExport, error) {
+	return nil, nil
+}
+func (m MockMovieModel) GetByExternalID(source, externalID string) (*Movie, error) {
+	return nil, nil
+}
+func (m MockMovieModel) UpsertByExternalID(source, externalID string, movie *Movie) (string, error) {
+	return "", nil
+}
+func (m MockMovieModel) FindDuplicates(threshold float64, yearTolerance, runtimeTolerance int, filters Filters) ([]*DuplicateCandidate, *Metadata, error) {
+	return nil, nil, nil
+}
+func (m MockMovieModel) Merge(targetID, sourceID int64, genres []string) (*Movie, error) {
+	return nil, nil
+}
+func (m MockMovieModel) Batch(ops []*BatchOp, atomic bool) error {
+	return nil
+}
+func (m MockMovieModel) GetMany(ids []int64) ([]*Movie, []int64, error) {
 	return nil, nil, nil
 }
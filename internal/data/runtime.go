@@ -8,6 +8,13 @@ import (
 )
 
 type Runtime int32
+
+// Bounds accepted for a runtime, in minutes.
+const (
+	MinRuntime = 2
+	MaxRuntime = 200
+)
+
 type RuntimeErr struct {
 	msg string
 }
@@ -36,8 +43,8 @@ func (r *Runtime) UnmarshalJSON(jsonValue []byte) error {
 		log.Println("Minutes should be integer")
 		return RuntimeErr{msg: "Minutes should be integer"}
 	}
-	if iStringSlice[1] != "mins" || duration < 2 || duration > 200 {
-		return RuntimeErr{msg: "Format is 'integer between 2 and 200' followed by ' mins'"}
+	if iStringSlice[1] != "mins" || duration < MinRuntime || duration > MaxRuntime {
+		return RuntimeErr{msg: fmt.Sprintf("Format is 'integer between %d and %d' followed by ' mins'", MinRuntime, MaxRuntime)}
 	}
 	*r = Runtime(duration)
 	log.Println(*r)
@@ -0,0 +1,101 @@
+package data
+
+import (
+	"context"
+	"fmt"
+	"html"
+	"strings"
+	"time"
+	"unicode"
+
+	"github.com/lib/pq"
+)
+
+// Text search configurations a search may ask for. The configuration is written
+// into the query rather than passed as a parameter, so that the planner can match
+// the expression indexes on to_tsvector(<config>, title).
+var searchConfigs = map[string]bool{
+	"simple":     true,
+	"english":    true,
+	"french":     true,
+	"german":     true,
+	"spanish":    true,
+	"italian":    true,
+	"portuguese": true,
+	"dutch":      true,
+}
+
+// prefixQuery turns free text into a to_tsquery expression where every word is
+// required and may be a prefix, e.g. "star wa" becomes "star:* & wa:*".
+func prefixQuery(q string) string {
+	words := strings.FieldsFunc(q, func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsDigit(r)
+	})
+	for i, w := range words {
+		words[i] = w + ":*"
+	}
+	return strings.Join(words, " & ")
+}
+
+// highlightHTML escapes a title highlighted by ts_headline, with \x02 and \x03
+// around the matches, and marks the matches with <mark>. Titles are text, so
+// ts_headline can't be given <mark> right away.
+func highlightHTML(h string) string {
+	return strings.NewReplacer("\x02", "<mark>", "\x03", "</mark>").Replace(html.EscapeString(h))
+}
+
+// Search ranks the movies matching the ListMovie filters by relevance to the
+// search text. Titles match on stemmed words and word prefixes, and thanks to
+// pg_trgm also when the text has a typo in it.
+func (m MovieModel) Search(sm SearchMovie) ([]*SearchResult, *Metadata, error) {
+	results := []*SearchResult{}
+	tr := 0
+	cfg := sm.Language
+	if !searchConfigs[cfg] {
+		cfg = "simple"
+	}
+	filters := sm.Filters
+	where, args := sm.ListMovie.where()
+	tsq, raw := len(args)+1, len(args)+2
+	qry := fmt.Sprintf(`SELECT count(*) OVER(), id, created_at, title, year, runtime, genres, version,
+	ts_rank(to_tsvector('%[1]v', title), to_tsquery('%[1]v', $%[3]d)) + word_similarity($%[4]d, title) AS score,
+	ts_headline('%[1]v', title, to_tsquery('%[1]v', $%[3]d), E'StartSel=\x02, StopSel=\x03, HighlightAll=true')
+	FROM movies
+	%[2]v
+	AND (to_tsvector('%[1]v', title) @@ to_tsquery('%[1]v', $%[3]d) OR $%[4]d <%% title)
+	ORDER BY score DESC, id ASC
+	LIMIT $%[5]d OFFSET $%[6]d`, cfg, where, tsq, raw, len(args)+3, len(args)+4)
+	args = append(args, prefixQuery(sm.Query), sm.Query, filters.limit(), filters.offset())
+	ctx, cancel := context.WithTimeout(context.Background(), timeout*time.Second)
+	defer cancel()
+	rows, err := m.DB.QueryContext(ctx, qry, args...)
+	if err != nil {
+		return nil, nil, err
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var result SearchResult
+		err := rows.Scan(
+			&tr,
+			&result.ID,
+			&result.CreatedAt,
+			&result.Title,
+			&result.Year,
+			&result.Runtime,
+			pq.Array(&result.Genres),
+			&result.Version,
+			&result.Score,
+			&result.Highlight,
+		)
+		if err != nil {
+			return nil, nil, err
+		}
+		result.Highlight = highlightHTML(result.Highlight)
+		results = append(results, &result)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, nil, err
+	}
+	metadata := calculateMetadata(tr, filters.Page, filters.PageSize)
+	return results, &metadata, nil
+}
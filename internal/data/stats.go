@@ -0,0 +1,154 @@
+package data
+
+import (
+	"context"
+	"database/sql"
+	"sync"
+	"time"
+)
+
+type YearCount struct {
+	Year  int32 `json:"year"`
+	Count int   `json:"count"`
+}
+
+type GenreCount struct {
+	Genre string `json:"genre"`
+	Count int    `json:"count"`
+}
+
+type DayCount struct {
+	Day   string `json:"day"`
+	Count int    `json:"count"`
+}
+
+type Stats struct {
+	TotalMovies    int           `json:"total_movies"`
+	AverageRuntime float64       `json:"average_runtime"`
+	AddedThisWeek  int           `json:"added_this_week"`
+	PerYear        []*YearCount  `json:"per_year"`
+	PerGenre       []*GenreCount `json:"per_genre"`
+	CreatedPerDay  []*DayCount   `json:"created_per_day,omitempty"`
+	GeneratedAt    time.Time     `json:"generated_at"`
+}
+
+type statsEntry struct {
+	stats   *Stats
+	expires time.Time
+}
+
+// StatsModel computes catalogue wide aggregates. They scan the whole movies table,
+// so results are kept for TTL before being computed again.
+type StatsModel struct {
+	DB  *sql.DB
+	TTL time.Duration
+	mu  *sync.Mutex
+	// Keyed by the number of days of the time series, 0 when not asked for
+	cache map[int]statsEntry
+}
+
+func NewStatsModel(db *sql.DB, ttl time.Duration) StatsModel {
+	return StatsModel{
+		DB:    db,
+		TTL:   ttl,
+		mu:    &sync.Mutex{},
+		cache: map[int]statsEntry{},
+	}
+}
+
+// Get returns the statistics of the catalogue, along with the number of movies
+// created per day over the last days when days is greater than 0.
+func (m StatsModel) Get(days int) (*Stats, error) {
+	m.mu.Lock()
+	entry, ok := m.cache[days]
+	m.mu.Unlock()
+	if ok && time.Now().Before(entry.expires) {
+		return entry.stats, nil
+	}
+	stats, err := m.compute(days)
+	if err != nil {
+		return nil, err
+	}
+	m.mu.Lock()
+	m.cache[days] = statsEntry{stats: stats, expires: stats.GeneratedAt.Add(m.TTL)}
+	m.mu.Unlock()
+	return stats, nil
+}
+
+func (m StatsModel) compute(days int) (*Stats, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), timeout*time.Second)
+	defer cancel()
+	stats := Stats{
+		PerYear:     []*YearCount{},
+		PerGenre:    []*GenreCount{},
+		GeneratedAt: time.Now().UTC(),
+	}
+	query := `SELECT count(*), COALESCE(avg(runtime), 0),
+	count(*) FILTER (WHERE created_at >= date_trunc('week', now()))
+	FROM movies`
+	err := m.DB.QueryRowContext(ctx, query).Scan(&stats.TotalMovies, &stats.AverageRuntime, &stats.AddedThisWeek)
+	if err != nil {
+		return nil, err
+	}
+
+	rows, err := m.DB.QueryContext(ctx, `SELECT year, count(*) FROM movies GROUP BY year ORDER BY year`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var yc YearCount
+		if err := rows.Scan(&yc.Year, &yc.Count); err != nil {
+			return nil, err
+		}
+		stats.PerYear = append(stats.PerYear, &yc)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	rows, err = m.DB.QueryContext(ctx, `SELECT g, count(*)
+	FROM movies, unnest(movies.genres) AS g
+	GROUP BY g
+	ORDER BY count(*) DESC, g ASC`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var gc GenreCount
+		if err := rows.Scan(&gc.Genre, &gc.Count); err != nil {
+			return nil, err
+		}
+		stats.PerGenre = append(stats.PerGenre, &gc)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	if days > 0 {
+		// generate_series yields the days without any movie too
+		query := `SELECT to_char(d, 'YYYY-MM-DD'), count(movies.id)
+		FROM generate_series(current_date - ($1::int - 1), current_date, interval '1 day') AS d
+		LEFT JOIN movies ON movies.created_at::date = d::date
+		GROUP BY d
+		ORDER BY d`
+		rows, err = m.DB.QueryContext(ctx, query, days)
+		if err != nil {
+			return nil, err
+		}
+		defer rows.Close()
+		stats.CreatedPerDay = []*DayCount{}
+		for rows.Next() {
+			var dc DayCount
+			if err := rows.Scan(&dc.Day, &dc.Count); err != nil {
+				return nil, err
+			}
+			stats.CreatedPerDay = append(stats.CreatedPerDay, &dc)
+		}
+		if err := rows.Err(); err != nil {
+			return nil, err
+		}
+	}
+	return &stats, nil
+}
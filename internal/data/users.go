@@ -8,6 +8,7 @@ import (
 	"fmt"
 	"time"
 
+	"github.com/lib/pq"
 	"golang.org/x/crypto/bcrypt"
 )
 
@@ -47,20 +48,54 @@ func (u *User) IsAnonymous() bool {
 }
 
 func (m UserModel) Insert(user *User) error {
+	ctx, cancel := context.WithTimeout(context.Background(), userTimeout*time.Second)
+	defer cancel()
+	tx, err := m.DB.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+	if err := insertUser(ctx, tx, user); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+// Register inserts a new user along with the given permissions and the built-in
+// lists, all or nothing.
+func (m UserModel) Register(user *User, permissions ...string) error {
+	ctx, cancel := context.WithTimeout(context.Background(), userTimeout*time.Second)
+	defer cancel()
+	tx, err := m.DB.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+	if err := insertUser(ctx, tx, user); err != nil {
+		return err
+	}
+	if _, err := tx.ExecContext(ctx, addPermissionsQuery, user.ID, pq.Array(permissions)); err != nil {
+		return err
+	}
+	if err := insertBuiltInLists(ctx, tx, user.ID); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+func insertUser(ctx context.Context, tx *sql.Tx, user *User) error {
 	query := `INSERT INTO users (name, email, password_hash, activated)
 	VALUES ($1, $2, $3, $4)
 	RETURNING id, created_at, version` // Returning is PSQL syntax
 	args := []interface{}{user.Name, user.Email, user.Password.hash, user.Activated}
-	ctx, cancel := context.WithTimeout(context.Background(), userTimeout*time.Second)
-	defer cancel()
-	err := m.DB.QueryRowContext(ctx, query, args...).Scan(&user.ID, &user.CreatedAt, &user.Version)
+	err := tx.QueryRowContext(ctx, query, args...).Scan(&user.ID, &user.CreatedAt, &user.Version)
 	if err != nil {
 		switch {
-		case err.Error() == `pq: duplicate key value violates unique constraint "users_email_key"`:
+		case isUniqueViolation(err, "users_email_key"):
 			// %w wraps error
 			return fmt.Errorf("%w", &ErrDupEmail{email: user.Email})
 		default:
-			return err
+			return dbError(err)
 		}
 	}
 	return nil
@@ -112,11 +147,11 @@ func (m UserModel) Update(user *User) error {
 	if err != nil {
 		switch {
 		case errors.Is(err, sql.ErrNoRows):
-			return fmt.Errorf("%w", ErrRecordNotFound)
-		case err.Error() == `pq: duplicate key value violates unique constraint "users_email_key"`:
+			return fmt.Errorf("%w", ErrEditConflict)
+		case isUniqueViolation(err, "users_email_key"):
 			return fmt.Errorf("%w", &ErrDupEmail{email: user.Email})
 		default:
-			return err
+			return dbError(err)
 		}
 	}
 	return nil
@@ -148,7 +183,7 @@ func (m UserModel) GetForToken(tokenScope, tokenPlaintext string) (*User, error)
 	if err != nil {
 		switch {
 		case errors.Is(err, sql.ErrNoRows):
-			return nil, err
+			return nil, fmt.Errorf("%w", ErrRecordNotFound)
 		default:
 			return nil, err
 		}
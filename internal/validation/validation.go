@@ -3,15 +3,16 @@ package validation
 import (
 	"errors"
 	"fmt"
-	"log"
 	"mdb/internal/data"
 	"strings"
+	"sync"
 	"time"
 
 	"github.com/go-playground/validator/v10"
 )
 
-const minYear = 1899
+// Years accepted by YearRange are after MinYear and up to the current year.
+const MinYear = 1899
 
 func Errors(err error) map[string]string {
 	var ve validator.ValidationErrors
@@ -26,16 +27,26 @@ func Errors(err error) map[string]string {
 			switch v.Tag() {
 			case "required":
 				msg = "Is needed"
-			case "unique", "genre":
+			case "email":
+				msg = "Should be a valid email address"
+			case "len":
+				msg = "Should be " + v.Param() + " characters long"
+			case "unique":
 				msg = "Values should be unique"
+			case "genre", "csvgenre":
+				msg = "Values should be unique and known genres, see /v1/genres"
 			case "max":
 				msg = "Should be less than " + v.Param()
 			case "min":
 				msg = "Should be greater than " + v.Param()
-			case "oneof":
+			case "oneof", "csvoneof":
 				msg = "Should be one of:" + v.Param()
+			case "gtefield":
+				msg = "Should be greater than or equal to " + v.Param()
+			case "runtimerange":
+				msg = fmt.Sprintf("Should be between %d and %d", data.MinRuntime, data.MaxRuntime)
 			case "yearrange":
-				msg = fmt.Sprintf("Should be less than or equal to %d and greater than %d", time.Now().Year(), minYear)
+				msg = fmt.Sprintf("Should be less than or equal to %d and greater than %d", time.Now().Year(), MinYear)
 			default:
 				msg = "Unknown error"
 			}
@@ -49,24 +60,103 @@ func Errors(err error) map[string]string {
 
 func YearRange(fl validator.FieldLevel) bool {
 	year := fl.Field().Int()
-	if int(year) <= time.Now().Year() && year > minYear {
+	if int(year) <= time.Now().Year() && year > MinYear {
 		return true
 	}
 	return false
 }
 
-func Genres(fl validator.FieldLevel) bool {
+// RuntimeRange applies the bounds of data.Runtime to fields which are not decoded
+// from JSON, like the runtime_min and runtime_max query parameters.
+func RuntimeRange(fl validator.FieldLevel) bool {
+	runtime := fl.Field().Int()
+	return runtime >= data.MinRuntime && runtime <= data.MaxRuntime
+}
+
+// Vocabulary is the in-memory copy of the genres table used to validate and
+// normalise the genres sent by clients. It is safe for concurrent use.
+type Vocabulary struct {
+	mu    sync.RWMutex
+	slugs map[string]string // slug or alias -> slug
+}
+
+func NewVocabulary() *Vocabulary {
+	return &Vocabulary{slugs: map[string]string{}}
+}
+
+// GenreKey is the form under which slugs and aliases are stored and looked up.
+func GenreKey(name string) string {
+	return strings.ToLower(strings.TrimSpace(name))
+}
+
+// Load replaces the vocabulary, it is called at start up and whenever genres change.
+func (v *Vocabulary) Load(genres []*data.Genre) {
+	slugs := make(map[string]string)
+	for _, g := range genres {
+		slugs[g.Slug] = g.Slug
+		for _, alias := range g.Aliases {
+			slugs[alias] = g.Slug
+		}
+	}
+	v.mu.Lock()
+	v.slugs = slugs
+	v.mu.Unlock()
+}
+
+// Canonical returns the slug of a genre given its slug or any of its aliases.
+func (v *Vocabulary) Canonical(name string) (string, bool) {
+	v.mu.RLock()
+	defer v.mu.RUnlock()
+	slug, ok := v.slugs[GenreKey(name)]
+	return slug, ok
+}
+
+// Normalize maps every genre to its slug and drops duplicates. Unknown genres are
+// kept as is, callers are expected to have validated the input with Genres.
+func (v *Vocabulary) Normalize(genres []string) []string {
+	out := make([]string, 0, len(genres))
+	seen := make(map[string]bool)
+	for _, g := range genres {
+		if slug, ok := v.Canonical(g); ok {
+			g = slug
+		}
+		if !seen[g] {
+			seen[g] = true
+			out = append(out, g)
+		}
+	}
+	return out
+}
+
+// Genres backs the "genre" tag of JSON bodies: every value must be a known genre
+// and values must stay unique once aliases are resolved. "drama,comedy" is one
+// unknown genre, not two.
+func (v *Vocabulary) Genres(fl validator.FieldLevel) bool {
 	// Underlying value is a slice of string, hence used interface method and then type casted to slice
-	genres := fl.Field().Interface().([]string)
-	// Len of genres is 1 although it is slice
-	splitGenres := strings.Split(genres[0], ",")
-	unique := make(map[string]string)
-	log.Println(splitGenres)
-	for _, v := range splitGenres {
-		unique[v] = v
+	return v.known(fl.Field().Interface().([]string))
+}
+
+// CSVGenres backs the "csvgenre" tag, the Genres of query strings. A query string
+// like genres=drama,sci-fi reaches here as a single comma separated element, hence
+// the split.
+func (v *Vocabulary) CSVGenres(fl validator.FieldLevel) bool {
+	var genres []string
+	for _, raw := range fl.Field().Interface().([]string) {
+		genres = append(genres, strings.Split(raw, ",")...)
+	}
+	return v.known(genres)
+}
+
+func (v *Vocabulary) known(genres []string) bool {
+	unique := make(map[string]bool)
+	for _, g := range genres {
+		slug, ok := v.Canonical(g)
+		if !ok || unique[slug] {
+			return false
+		}
+		unique[slug] = true
 	}
-	log.Println(unique)
-	return len(splitGenres) == len(unique)
+	return true
 }
 
 func OneOf(fl validator.FieldLevel) bool {
@@ -79,3 +169,24 @@ func OneOf(fl validator.FieldLevel) bool {
 	}
 	return false
 }
+
+// CSVOneOf is OneOf for query parameters holding a comma separated list, like
+// facets=genres,decade. Every item must be one of the space separated params.
+func CSVOneOf(fl validator.FieldLevel) bool {
+	match := strings.Split(fl.Param(), " ")
+	for _, raw := range fl.Field().Interface().([]string) {
+		for _, item := range strings.Split(raw, ",") {
+			found := false
+			for _, v := range match {
+				if v == item {
+					found = true
+					break
+				}
+			}
+			if !found {
+				return false
+			}
+		}
+	}
+	return true
+}
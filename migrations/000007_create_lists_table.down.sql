@@ -0,0 +1,2 @@
+DROP TABLE IF EXISTS lists_movies;
+DROP TABLE IF EXISTS lists;
@@ -0,0 +1,25 @@
+CREATE TABLE IF NOT EXISTS lists (
+    id bigserial PRIMARY KEY,
+    created_at timestamp(0) with time zone NOT NULL DEFAULT NOW(),
+    user_id bigint NOT NULL REFERENCES users ON DELETE CASCADE,
+    name text NOT NULL,
+    kind text NOT NULL DEFAULT 'custom' CHECK (kind IN ('custom', 'to_watch', 'watched')),
+    public bool NOT NULL DEFAULT false,
+    version integer NOT NULL DEFAULT 1,
+    UNIQUE (user_id, name)
+);
+
+-- A user has one list of each built-in kind
+CREATE UNIQUE INDEX IF NOT EXISTS lists_user_id_kind_idx ON lists (user_id, kind) WHERE kind <> 'custom';
+
+-- Users registered before lists existed get the built-in ones
+INSERT INTO lists (user_id, name, kind)
+SELECT users.id, builtin.name, builtin.kind
+FROM users CROSS JOIN (VALUES ('To watch', 'to_watch'), ('Watched', 'watched')) AS builtin (name, kind);
+
+CREATE TABLE IF NOT EXISTS lists_movies (
+    list_id bigint NOT NULL REFERENCES lists ON DELETE CASCADE,
+    movie_id bigint NOT NULL REFERENCES movies ON DELETE CASCADE,
+    position integer NOT NULL,
+    PRIMARY KEY (list_id, movie_id)
+);
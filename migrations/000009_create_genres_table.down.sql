@@ -0,0 +1,4 @@
+-- The normalisation of movies.genres done by the up migration is not reverted.
+DELETE FROM permissions WHERE code = 'genres:write';
+DROP TABLE IF EXISTS genre_aliases;
+DROP TABLE IF EXISTS genres;
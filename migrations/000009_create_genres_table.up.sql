@@ -0,0 +1,69 @@
+CREATE TABLE IF NOT EXISTS genres (
+    id bigserial PRIMARY KEY,
+    slug text UNIQUE NOT NULL,
+    name text NOT NULL
+);
+
+-- Aliases are stored lower cased and trimmed, the same key the API uses for lookups.
+CREATE TABLE IF NOT EXISTS genre_aliases (
+    alias text PRIMARY KEY,
+    genre_id bigint NOT NULL REFERENCES genres ON DELETE CASCADE
+);
+
+INSERT INTO permissions (code)
+VALUES ('genres:write');
+
+INSERT INTO genres (slug, name)
+VALUES ('action', 'Action'), ('adventure', 'Adventure'), ('animation', 'Animation'),
+('comedy', 'Comedy'), ('crime', 'Crime'), ('documentary', 'Documentary'), ('drama', 'Drama'),
+('family', 'Family'), ('fantasy', 'Fantasy'), ('history', 'History'), ('horror', 'Horror'),
+('music', 'Music'), ('mystery', 'Mystery'), ('romance', 'Romance'),
+('sci-fi', 'Science Fiction'), ('thriller', 'Thriller'), ('war', 'War'), ('western', 'Western')
+ON CONFLICT (slug) DO NOTHING;
+
+INSERT INTO genre_aliases (alias, genre_id)
+SELECT a.alias, genres.id
+FROM (VALUES ('science fiction', 'sci-fi'), ('science-fiction', 'sci-fi'), ('scifi', 'sci-fi'),
+('sf', 'sci-fi'), ('animated', 'animation'), ('romcom', 'comedy'), ('docu', 'documentary'))
+AS a(alias, slug)
+INNER JOIN genres ON genres.slug = a.slug
+ON CONFLICT (alias) DO NOTHING;
+
+-- Every genre already used by a movie which is neither a slug nor an alias becomes a
+-- genre of its own, with the raw value kept as an alias when it differs from the slug.
+INSERT INTO genres (slug, name)
+SELECT DISTINCT lower(regexp_replace(trim(g), '\s+', '-', 'g')), trim(g)
+FROM movies, unnest(movies.genres) AS g
+WHERE lower(trim(g)) NOT IN (SELECT slug FROM genres)
+AND lower(trim(g)) NOT IN (SELECT alias FROM genre_aliases)
+ON CONFLICT (slug) DO NOTHING;
+
+INSERT INTO genre_aliases (alias, genre_id)
+SELECT DISTINCT lower(trim(g)), genres.id
+FROM movies, unnest(movies.genres) AS g
+INNER JOIN genres ON genres.slug = lower(regexp_replace(trim(g), '\s+', '-', 'g'))
+WHERE lower(trim(g)) <> genres.slug
+ON CONFLICT (alias) DO NOTHING;
+
+-- Rewrite the genres of every movie to canonical slugs, dropping the duplicates which
+-- appear once aliases are resolved while keeping the original order.
+WITH lookup AS (
+    SELECT slug AS key, slug FROM genres
+    UNION ALL
+    SELECT genre_aliases.alias, genres.slug
+    FROM genre_aliases INNER JOIN genres ON genres.id = genre_aliases.genre_id
+), normalised AS (
+    SELECT movies.id, ARRAY(
+        SELECT u.slug FROM (
+            SELECT lookup.slug, min(t.ord) AS ord
+            FROM unnest(movies.genres) WITH ORDINALITY AS t(g, ord)
+            INNER JOIN lookup ON lookup.key = lower(trim(t.g))
+            GROUP BY lookup.slug
+        ) AS u ORDER BY u.ord
+    ) AS genres
+    FROM movies
+)
+UPDATE movies
+SET genres = normalised.genres, version = movies.version + 1
+FROM normalised
+WHERE movies.id = normalised.id AND movies.genres <> normalised.genres;
@@ -0,0 +1,7 @@
+CREATE TABLE IF NOT EXISTS movie_external_ids (
+    source text NOT NULL,
+    external_id text NOT NULL,
+    movie_id bigint NOT NULL REFERENCES movies ON DELETE CASCADE,
+    PRIMARY KEY (source, external_id),
+    UNIQUE (movie_id, source)
+);
@@ -0,0 +1,303 @@
+// Package client is a Go client of the mdb API. It reuses the types of the data
+// package, gets and refreshes the bearer token from the configured credentials
+// and retries requests which were rate limited or hit a server error.
+//
+//	c := client.New("http://localhost:4000", client.Config{Email: email, Password: password})
+//	list, err := c.ListMovies(ctx, data.ListMovie{Title: "alien"})
+package client
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"math/rand"
+	"mdb/internal/data"
+	"net/http"
+	"net/url"
+	"reflect"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Tokens expiring sooner than this are refreshed before a request
+const tokenLeeway = time.Minute
+
+type Config struct {
+	// HTTPClient defaults to a client with a 30 seconds timeout
+	HTTPClient *http.Client
+	// Credentials used to get a token, and a new one once it expires
+	Email    string
+	Password string
+	// Token is a token obtained earlier, e.g. stored by a CLI. It is used until it
+	// expires or is rejected, then the credentials take over if any.
+	Token       string
+	TokenExpiry time.Time
+	// MaxRetries of a request answered with 429 or a 5xx, 3 when 0 and none when
+	// negative
+	MaxRetries int
+	// RetryWait is the first backoff, doubled on every retry, 500ms when 0
+	RetryWait time.Duration
+	// UserAgent sent along every request
+	UserAgent string
+}
+
+// Client is safe for concurrent use.
+type Client struct {
+	baseURL string
+	cfg     Config
+
+	mu     sync.Mutex
+	token  string
+	expiry time.Time
+}
+
+func New(baseURL string, cfg Config) *Client {
+	if cfg.HTTPClient == nil {
+		cfg.HTTPClient = &http.Client{Timeout: 30 * time.Second}
+	}
+	if cfg.MaxRetries == 0 {
+		cfg.MaxRetries = 3
+	}
+	if cfg.RetryWait == 0 {
+		cfg.RetryWait = 500 * time.Millisecond
+	}
+	if cfg.UserAgent == "" {
+		cfg.UserAgent = "mdb-go-client"
+	}
+	return &Client{
+		baseURL: strings.TrimRight(baseURL, "/"),
+		cfg:     cfg,
+		token:   cfg.Token,
+		expiry:  cfg.TokenExpiry,
+	}
+}
+
+// Token returns the current token and its expiry, so that it can be stored and
+// given back through Config.Token. It is empty until a token was obtained.
+func (c *Client) Token() (string, time.Time) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.token, c.expiry
+}
+
+// Authenticate exchanges the credentials for a token, which the client uses from
+// now on.
+func (c *Client) Authenticate(ctx context.Context, email, password string) (*data.Token, error) {
+	var out struct {
+		Token data.Token `json:"token"`
+	}
+	body := map[string]string{"email": email, "password": password}
+	if err := c.send(ctx, http.MethodPost, "/v1/tokens/authentication", nil, body, &out, false); err != nil {
+		return nil, err
+	}
+	c.mu.Lock()
+	c.token, c.expiry = out.Token.Plaintext, out.Token.Expiry
+	c.mu.Unlock()
+	return &out.Token, nil
+}
+
+// bearer returns a token valid for a while, getting a new one from the
+// credentials when needed. It returns "" when there is no way to authenticate.
+func (c *Client) bearer(ctx context.Context, refresh bool) (string, error) {
+	c.mu.Lock()
+	token, expiry := c.token, c.expiry
+	c.mu.Unlock()
+	fresh := token != "" && (expiry.IsZero() || time.Until(expiry) > tokenLeeway)
+	if fresh && !refresh {
+		return token, nil
+	}
+	if c.cfg.Email == "" {
+		return token, nil
+	}
+	t, err := c.Authenticate(ctx, c.cfg.Email, c.cfg.Password)
+	if err != nil {
+		return "", err
+	}
+	return t.Plaintext, nil
+}
+
+// do sends a JSON request with a bearer token and decodes the response into out.
+func (c *Client) do(ctx context.Context, method, path string, query url.Values, in, out any) error {
+	return c.send(ctx, method, path, query, in, out, true)
+}
+
+func (c *Client) send(ctx context.Context, method, path string, query url.Values, in, out any, auth bool) error {
+	var body []byte
+	contentType := ""
+	switch v := in.(type) {
+	case nil:
+	case rawBody:
+		body, contentType = v.data, v.contentType
+	default:
+		js, err := json.Marshal(in)
+		if err != nil {
+			return err
+		}
+		body, contentType = js, "application/json"
+	}
+	u := c.baseURL + path
+	if len(query) > 0 {
+		u += "?" + query.Encode()
+	}
+
+	refreshed := false
+	for attempt := 0; ; {
+		req, err := http.NewRequestWithContext(ctx, method, u, bytes.NewReader(body))
+		if err != nil {
+			return err
+		}
+		req.Header.Set("Accept", "application/json")
+		req.Header.Set("User-Agent", c.cfg.UserAgent)
+		if contentType != "" {
+			req.Header.Set("Content-Type", contentType)
+		}
+		if auth {
+			token, err := c.bearer(ctx, false)
+			if err != nil {
+				return err
+			}
+			if token != "" {
+				req.Header.Set("Authorization", "Bearer "+token)
+			}
+		}
+
+		resp, err := c.cfg.HTTPClient.Do(req)
+		if err != nil {
+			return err
+		}
+		if resp.StatusCode < 300 {
+			return decodeResponse(resp, out)
+		}
+		respBody, err := io.ReadAll(resp.Body)
+		resp.Body.Close()
+		if err != nil {
+			return err
+		}
+
+		// A token which expired or was revoked early is replaced once
+		if resp.StatusCode == http.StatusUnauthorized && auth && !refreshed && c.cfg.Email != "" {
+			refreshed = true
+			if _, err := c.bearer(ctx, true); err != nil {
+				return err
+			}
+			continue
+		}
+		if attempt < c.cfg.MaxRetries && retryable(method, resp.StatusCode) {
+			if err := sleep(ctx, c.backoff(attempt, resp.Header.Get("Retry-After"))); err != nil {
+				return err
+			}
+			attempt++
+			continue
+		}
+		return newError(resp, respBody)
+	}
+}
+
+// decodeResponse decodes a successful response into out, or copies it when out is
+// an io.Writer.
+func decodeResponse(resp *http.Response, out any) error {
+	defer resp.Body.Close()
+	switch w := out.(type) {
+	case nil:
+		_, err := io.Copy(io.Discard, resp.Body)
+		return err
+	case io.Writer:
+		_, err := io.Copy(w, resp.Body)
+		return err
+	}
+	return json.NewDecoder(resp.Body).Decode(out)
+}
+
+// retryable tells whether a request can be sent again. A rate limited request
+// was not run, a server error may have been run half way so it is only retried
+// for the idempotent methods.
+func retryable(method string, status int) bool {
+	if status == http.StatusTooManyRequests {
+		return true
+	}
+	if status < http.StatusInternalServerError || status == http.StatusNotImplemented {
+		return false
+	}
+	switch method {
+	case http.MethodGet, http.MethodHead, http.MethodPut, http.MethodDelete:
+		return true
+	}
+	return false
+}
+
+// backoff waits the Retry-After header when the server sent one, otherwise it
+// doubles RetryWait on every attempt with some jitter.
+func (c *Client) backoff(attempt int, retryAfter string) time.Duration {
+	if secs, err := strconv.Atoi(retryAfter); err == nil && secs >= 0 {
+		return time.Duration(secs) * time.Second
+	}
+	wait := c.cfg.RetryWait << attempt
+	return wait/2 + time.Duration(rand.Int63n(int64(wait/2)+1))
+}
+
+func sleep(ctx context.Context, d time.Duration) error {
+	t := time.NewTimer(d)
+	defer t.Stop()
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-t.C:
+		return nil
+	}
+}
+
+// rawBody is a request body which is not JSON, like a CSV upload.
+type rawBody struct {
+	contentType string
+	data        []byte
+}
+
+// queryValues encodes the form tags of v the way gin binds them, embedded
+// structs included. Zero values are left out.
+func queryValues(v any) url.Values {
+	q := url.Values{}
+	addQuery(q, reflect.ValueOf(v))
+	return q
+}
+
+func addQuery(q url.Values, v reflect.Value) {
+	for v.Kind() == reflect.Pointer {
+		if v.IsNil() {
+			return
+		}
+		v = v.Elem()
+	}
+	t := v.Type()
+	for i := 0; i < t.NumField(); i++ {
+		f := t.Field(i)
+		name, _, _ := strings.Cut(f.Tag.Get("form"), ",")
+		if name == "-" {
+			continue
+		}
+		fv := v.Field(i)
+		if f.Anonymous && name == "" && fv.Kind() == reflect.Struct {
+			addQuery(q, fv)
+			continue
+		}
+		if !f.IsExported() || fv.IsZero() {
+			continue
+		}
+		if name == "" {
+			name = f.Name
+		}
+		// The API reads lists as one comma separated value, genres=drama,comedy
+		if fv.Kind() == reflect.Slice {
+			items := make([]string, fv.Len())
+			for j := range items {
+				items[j] = fmt.Sprint(fv.Index(j).Interface())
+			}
+			q.Set(name, strings.Join(items, ","))
+			continue
+		}
+		q.Set(name, fmt.Sprint(fv.Interface()))
+	}
+}
@@ -0,0 +1,102 @@
+package client
+
+import (
+	"context"
+	"errors"
+	"mdb/internal/data"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestQueryValues(t *testing.T) {
+	q := queryValues(data.ListMovie{
+		Title:   "alien",
+		Genres:  []string{"horror", "sci-fi"},
+		YearMin: 1979,
+		Fields:  []string{"id", "title"},
+		Filters: data.Filters{PageSize: 20, Sort: "-year"},
+	})
+	want := map[string]string{
+		"title":     "alien",
+		"genres":    "horror,sci-fi",
+		"year_min":  "1979",
+		"fields":    "id,title",
+		"page_size": "20",
+		"sort":      "-year",
+	}
+	for name, v := range want {
+		if got := q[name]; len(got) != 1 || got[0] != v {
+			t.Errorf("%v: got %q, want %q", name, got, v)
+		}
+	}
+	// Zero values are left out
+	if len(q) != len(want) {
+		t.Errorf("got %v, want only %v", q, want)
+	}
+}
+
+func TestRetry(t *testing.T) {
+	var calls int32
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if atomic.AddInt32(&calls, 1) < 3 {
+			w.Header().Set("Content-Type", "application/problem+json")
+			w.WriteHeader(http.StatusServiceUnavailable)
+			w.Write([]byte(`{"title":"Service Unavailable","status":503}`))
+			return
+		}
+		w.Write([]byte(`{"movie":{"id":1,"title":"Alien"}}`))
+	}))
+	defer srv.Close()
+
+	c := New(srv.URL, Config{Token: "t", RetryWait: time.Millisecond})
+	details, err := c.GetMovie(context.Background(), 1)
+	if err != nil {
+		t.Fatalf("GetMovie: %v", err)
+	}
+	if details.Movie.Title != "Alien" || calls != 3 {
+		t.Errorf("got %q after %d calls, want Alien after 3", details.Movie.Title, calls)
+	}
+
+	// A POST which hit a server error may have been run, it is not sent again
+	calls = 0
+	_, err = c.CreateMovie(context.Background(), &data.Movie{Title: "Alien"})
+	if !errors.Is(err, ErrServer) || calls != 1 {
+		t.Errorf("got %v after %d calls, want ErrServer after 1", err, calls)
+	}
+}
+
+func TestTokenRefresh(t *testing.T) {
+	var logins int32
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/v1/tokens/authentication" {
+			atomic.AddInt32(&logins, 1)
+			w.Write([]byte(`{"token":{"token":"fresh","expiry":"2100-01-01T00:00:00Z"}}`))
+			return
+		}
+		if r.Header.Get("Authorization") != "Bearer fresh" {
+			w.WriteHeader(http.StatusUnauthorized)
+			w.Write([]byte(`{"title":"Unauthorized","status":401}`))
+			return
+		}
+		w.Write([]byte(`{"movie":{"id":1,"title":"Alien"}}`))
+	}))
+	defer srv.Close()
+
+	// The stored token was revoked, the credentials get a new one
+	c := New(srv.URL, Config{Email: "a@example.com", Password: "secret", Token: "revoked", RetryWait: time.Millisecond})
+	if _, err := c.GetMovie(context.Background(), 1); err != nil {
+		t.Fatalf("GetMovie: %v", err)
+	}
+	if token, _ := c.Token(); token != "fresh" || logins != 1 {
+		t.Errorf("got token %q after %d logins, want fresh after 1", token, logins)
+	}
+
+	// Without credentials the 401 is the caller's
+	c = New(srv.URL, Config{Token: "revoked"})
+	if _, err := c.GetMovie(context.Background(), 1); !errors.Is(err, ErrUnauthorized) {
+		t.Errorf("got %v, want ErrUnauthorized", err)
+	}
+}
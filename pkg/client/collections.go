@@ -0,0 +1,84 @@
+package client
+
+import (
+	"context"
+	"fmt"
+	"mdb/internal/data"
+	"net/http"
+)
+
+// CollectionDetails is a collection along with its movies, in order.
+type CollectionDetails struct {
+	Collection *data.Collection `json:"collection"`
+	Movies     []*data.Movie    `json:"movies"`
+}
+
+type collectionEnvelope struct {
+	Collection *data.Collection `json:"collection"`
+}
+
+func collectionPath(id int64) string {
+	return fmt.Sprintf("/v1/collections/%d", id)
+}
+
+func (c *Client) collectionDetails(ctx context.Context, method, path string, in any) (*CollectionDetails, error) {
+	var out CollectionDetails
+	if err := c.do(ctx, method, path, nil, in, &out); err != nil {
+		return nil, err
+	}
+	return &out, nil
+}
+
+func (c *Client) ListCollections(ctx context.Context) ([]*data.Collection, error) {
+	var out struct {
+		Collections []*data.Collection `json:"collections"`
+	}
+	if err := c.do(ctx, http.MethodGet, "/v1/collections", nil, nil, &out); err != nil {
+		return nil, err
+	}
+	return out.Collections, nil
+}
+
+func (c *Client) GetCollection(ctx context.Context, id int64) (*CollectionDetails, error) {
+	return c.collectionDetails(ctx, http.MethodGet, collectionPath(id), nil)
+}
+
+func (c *Client) CreateCollection(ctx context.Context, name, description string) (*data.Collection, error) {
+	in := map[string]string{"name": name, "description": description}
+	var out collectionEnvelope
+	if err := c.do(ctx, http.MethodPost, "/v1/collections", nil, in, &out); err != nil {
+		return nil, err
+	}
+	return out.Collection, nil
+}
+
+// UpdateCollection changes the fields which are not nil.
+func (c *Client) UpdateCollection(ctx context.Context, id int64, name, description *string) (*data.Collection, error) {
+	in := struct {
+		Name        *string `json:"name,omitempty"`
+		Description *string `json:"description,omitempty"`
+	}{name, description}
+	var out collectionEnvelope
+	if err := c.do(ctx, http.MethodPatch, collectionPath(id), nil, in, &out); err != nil {
+		return nil, err
+	}
+	return out.Collection, nil
+}
+
+func (c *Client) DeleteCollection(ctx context.Context, id int64) error {
+	return c.do(ctx, http.MethodDelete, collectionPath(id), nil, nil, nil)
+}
+
+func (c *Client) AddCollectionMovie(ctx context.Context, id, movieID int64) (*CollectionDetails, error) {
+	return c.collectionDetails(ctx, http.MethodPost, collectionPath(id)+"/movies", map[string]int64{"movie_id": movieID})
+}
+
+func (c *Client) RemoveCollectionMovie(ctx context.Context, id, movieID int64) (*CollectionDetails, error) {
+	return c.collectionDetails(ctx, http.MethodDelete, fmt.Sprintf("%v/movies/%d", collectionPath(id), movieID), nil)
+}
+
+// ReorderCollectionMovies sets the order of the movies, movieIDs has to list
+// every movie of the collection exactly once.
+func (c *Client) ReorderCollectionMovies(ctx context.Context, id int64, movieIDs []int64) (*CollectionDetails, error) {
+	return c.collectionDetails(ctx, http.MethodPut, collectionPath(id)+"/movies", map[string][]int64{"movie_ids": movieIDs})
+}
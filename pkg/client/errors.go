@@ -0,0 +1,100 @@
+package client
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+)
+
+// Kinds of failure, an *Error matches the one of its status with errors.Is
+var (
+	ErrBadRequest   = errors.New("bad request")
+	ErrUnauthorized = errors.New("unauthorized")
+	ErrForbidden    = errors.New("forbidden")
+	ErrNotFound     = errors.New("not found")
+	ErrConflict     = errors.New("conflict")
+	ErrInvalid      = errors.New("invalid request")
+	ErrRateLimited  = errors.New("rate limited")
+	ErrServer       = errors.New("server error")
+)
+
+// Error is a RFC 7807 problem sent by the API.
+type Error struct {
+	Status    int               `json:"status"`
+	Type      string            `json:"type"`
+	Title     string            `json:"title"`
+	Detail    string            `json:"detail"`
+	Instance  string            `json:"instance"`
+	RequestID string            `json:"request_id"`
+	Errors    map[string]string `json:"errors"`
+	// Extension members, e.g. the results of a failed atomic batch
+	Extensions map[string]json.RawMessage `json:"-"`
+}
+
+// newError reads the problem of a failed response. Responses which are not a
+// problem, like those of a proxy, keep their body as the detail.
+func newError(resp *http.Response, body []byte) *Error {
+	e := &Error{}
+	if err := json.Unmarshal(body, e); err != nil || e.Title == "" {
+		e = &Error{Title: http.StatusText(resp.StatusCode), Detail: strings.TrimSpace(string(body))}
+	}
+	e.Status = resp.StatusCode
+	if e.RequestID == "" {
+		e.RequestID = resp.Header.Get("X-Request-ID")
+	}
+	return e
+}
+
+func (e *Error) UnmarshalJSON(js []byte) error {
+	type plain Error
+	if err := json.Unmarshal(js, (*plain)(e)); err != nil {
+		return err
+	}
+	var members map[string]json.RawMessage
+	if err := json.Unmarshal(js, &members); err != nil {
+		return err
+	}
+	for _, k := range []string{"status", "type", "title", "detail", "instance", "request_id", "errors"} {
+		delete(members, k)
+	}
+	if len(members) > 0 {
+		e.Extensions = members
+	}
+	return nil
+}
+
+func (e *Error) Error() string {
+	msg := fmt.Sprintf("%d %v", e.Status, e.Title)
+	if e.Detail != "" {
+		msg += ": " + e.Detail
+	}
+	for field, err := range e.Errors {
+		msg += fmt.Sprintf("; %v: %v", field, err)
+	}
+	return msg
+}
+
+// Is matches the Err variable of the status.
+func (e *Error) Is(target error) bool {
+	switch target {
+	case ErrBadRequest:
+		return e.Status == http.StatusBadRequest
+	case ErrUnauthorized:
+		return e.Status == http.StatusUnauthorized
+	case ErrForbidden:
+		return e.Status == http.StatusForbidden
+	case ErrNotFound:
+		return e.Status == http.StatusNotFound
+	case ErrConflict:
+		return e.Status == http.StatusConflict
+	case ErrInvalid:
+		return e.Status == http.StatusUnprocessableEntity || len(e.Errors) > 0
+	case ErrRateLimited:
+		return e.Status == http.StatusTooManyRequests
+	case ErrServer:
+		return e.Status >= http.StatusInternalServerError
+	}
+	return false
+}
@@ -0,0 +1,54 @@
+package client
+
+import (
+	"context"
+	"mdb/internal/data"
+	"net/http"
+	"net/url"
+)
+
+// Every change to the genres answers with the whole vocabulary
+type genresEnvelope struct {
+	Genres []*data.Genre `json:"genres"`
+}
+
+func genrePath(slug string) string {
+	return "/v1/genres/" + url.PathEscape(slug)
+}
+
+func (c *Client) genres(ctx context.Context, method, path string, in any) ([]*data.Genre, error) {
+	var out genresEnvelope
+	if err := c.do(ctx, method, path, nil, in, &out); err != nil {
+		return nil, err
+	}
+	return out.Genres, nil
+}
+
+func (c *Client) ListGenres(ctx context.Context) ([]*data.Genre, error) {
+	return c.genres(ctx, http.MethodGet, "/v1/genres", nil)
+}
+
+func (c *Client) CreateGenre(ctx context.Context, genre *data.Genre) ([]*data.Genre, error) {
+	return c.genres(ctx, http.MethodPost, "/v1/genres", genre)
+}
+
+func (c *Client) RenameGenre(ctx context.Context, slug, name string) ([]*data.Genre, error) {
+	return c.genres(ctx, http.MethodPatch, genrePath(slug), map[string]string{"name": name})
+}
+
+func (c *Client) DeleteGenre(ctx context.Context, slug string) ([]*data.Genre, error) {
+	return c.genres(ctx, http.MethodDelete, genrePath(slug), nil)
+}
+
+func (c *Client) AddGenreAlias(ctx context.Context, slug, alias string) ([]*data.Genre, error) {
+	return c.genres(ctx, http.MethodPost, genrePath(slug)+"/aliases", map[string]string{"alias": alias})
+}
+
+func (c *Client) RemoveGenreAlias(ctx context.Context, slug, alias string) ([]*data.Genre, error) {
+	return c.genres(ctx, http.MethodDelete, genrePath(slug)+"/aliases/"+url.PathEscape(alias), nil)
+}
+
+// MergeGenre folds the genre slug into the genre into.
+func (c *Client) MergeGenre(ctx context.Context, slug, into string) ([]*data.Genre, error) {
+	return c.genres(ctx, http.MethodPost, genrePath(slug)+"/merge", map[string]string{"into": into})
+}
@@ -0,0 +1,97 @@
+package client
+
+import (
+	"context"
+	"fmt"
+	"mdb/internal/data"
+	"net/http"
+)
+
+// ListDetails is a list along with a page of its movies.
+type ListDetails struct {
+	List     *data.List    `json:"list"`
+	Movies   []*data.Movie `json:"movies"`
+	Metadata data.Metadata `json:"metadata"`
+}
+
+type listEnvelope struct {
+	List *data.List `json:"list"`
+}
+
+func listPath(id int64) string {
+	return fmt.Sprintf("/v1/users/me/lists/%d", id)
+}
+
+// listDetails sends a request answered with a list and the page of its movies
+// selected by filters.
+func (c *Client) listDetails(ctx context.Context, method, path string, filters data.Filters, in any) (*ListDetails, error) {
+	var out ListDetails
+	if err := c.do(ctx, method, path, queryValues(filters), in, &out); err != nil {
+		return nil, err
+	}
+	return &out, nil
+}
+
+// ListLists returns the lists of the current user.
+func (c *Client) ListLists(ctx context.Context) ([]*data.List, error) {
+	var out struct {
+		Lists []*data.List `json:"lists"`
+	}
+	if err := c.do(ctx, http.MethodGet, "/v1/users/me/lists", nil, nil, &out); err != nil {
+		return nil, err
+	}
+	return out.Lists, nil
+}
+
+// GetList returns a list of the current user.
+func (c *Client) GetList(ctx context.Context, id int64, filters data.Filters) (*ListDetails, error) {
+	return c.listDetails(ctx, http.MethodGet, listPath(id), filters, nil)
+}
+
+// GetPublicList returns a list shared by any user, or one of the current user.
+func (c *Client) GetPublicList(ctx context.Context, id int64, filters data.Filters) (*ListDetails, error) {
+	return c.listDetails(ctx, http.MethodGet, fmt.Sprintf("/v1/lists/%d", id), filters, nil)
+}
+
+func (c *Client) CreateList(ctx context.Context, name string, public bool) (*data.List, error) {
+	in := struct {
+		Name   string `json:"name"`
+		Public bool   `json:"public"`
+	}{name, public}
+	var out listEnvelope
+	if err := c.do(ctx, http.MethodPost, "/v1/users/me/lists", nil, in, &out); err != nil {
+		return nil, err
+	}
+	return out.List, nil
+}
+
+// UpdateList changes the fields which are not nil.
+func (c *Client) UpdateList(ctx context.Context, id int64, name *string, public *bool) (*data.List, error) {
+	in := struct {
+		Name   *string `json:"name,omitempty"`
+		Public *bool   `json:"public,omitempty"`
+	}{name, public}
+	var out listEnvelope
+	if err := c.do(ctx, http.MethodPatch, listPath(id), nil, in, &out); err != nil {
+		return nil, err
+	}
+	return out.List, nil
+}
+
+func (c *Client) DeleteList(ctx context.Context, id int64) error {
+	return c.do(ctx, http.MethodDelete, listPath(id), nil, nil, nil)
+}
+
+func (c *Client) AddListMovie(ctx context.Context, id, movieID int64) (*ListDetails, error) {
+	return c.listDetails(ctx, http.MethodPost, listPath(id)+"/movies", data.Filters{}, map[string]int64{"movie_id": movieID})
+}
+
+func (c *Client) RemoveListMovie(ctx context.Context, id, movieID int64) (*ListDetails, error) {
+	return c.listDetails(ctx, http.MethodDelete, fmt.Sprintf("%v/movies/%d", listPath(id), movieID), data.Filters{}, nil)
+}
+
+// ReorderListMovies sets the order of the movies, movieIDs has to list every
+// movie of the list exactly once.
+func (c *Client) ReorderListMovies(ctx context.Context, id int64, movieIDs []int64) (*ListDetails, error) {
+	return c.listDetails(ctx, http.MethodPut, listPath(id)+"/movies", data.Filters{}, map[string][]int64{"movie_ids": movieIDs})
+}
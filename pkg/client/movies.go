@@ -0,0 +1,299 @@
+package client
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"mdb/internal/data"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+)
+
+// MovieDetails is a movie along with the collections it belongs to.
+type MovieDetails struct {
+	Movie       *data.Movie        `json:"movie"`
+	Collections []*data.Collection `json:"collections"`
+}
+
+// MovieList is a page of movies, with the facets which were asked for.
+type MovieList struct {
+	Movies   []*data.Movie                 `json:"movies"`
+	Metadata data.Metadata                 `json:"metadata"`
+	Facets   map[string][]*data.FacetCount `json:"facets"`
+}
+
+type movieEnvelope struct {
+	Movie *data.Movie `json:"movie"`
+}
+
+func moviePath(id int64) string {
+	return fmt.Sprintf("/v1/movies/%d", id)
+}
+
+func (c *Client) CreateMovie(ctx context.Context, movie *data.Movie) (*data.Movie, error) {
+	var out movieEnvelope
+	if err := c.do(ctx, http.MethodPost, "/v1/movies", nil, movie, &out); err != nil {
+		return nil, err
+	}
+	return out.Movie, nil
+}
+
+// GetMovie returns the movie with the given id. When fields are given only those
+// are read, the others are left to their zero value.
+func (c *Client) GetMovie(ctx context.Context, id int64, fields ...string) (*MovieDetails, error) {
+	var q url.Values
+	if len(fields) > 0 {
+		q = url.Values{"fields": {strings.Join(fields, ",")}}
+	}
+	var out MovieDetails
+	if err := c.do(ctx, http.MethodGet, moviePath(id), q, nil, &out); err != nil {
+		return nil, err
+	}
+	return &out, nil
+}
+
+// GetMovies returns the movies with the given ids in the same order, along with
+// the ids which match no movie.
+func (c *Client) GetMovies(ctx context.Context, ids ...int64) ([]*data.Movie, []int64, error) {
+	s := make([]string, len(ids))
+	for i, id := range ids {
+		s[i] = strconv.FormatInt(id, 10)
+	}
+	var out struct {
+		Movies  []*data.Movie `json:"movies"`
+		Missing []int64       `json:"missing"`
+	}
+	q := url.Values{"ids": {strings.Join(s, ",")}}
+	if err := c.do(ctx, http.MethodGet, "/v1/movies", q, nil, &out); err != nil {
+		return nil, nil, err
+	}
+	return out.Movies, out.Missing, nil
+}
+
+func (c *Client) ListMovies(ctx context.Context, filters data.ListMovie) (*MovieList, error) {
+	var out MovieList
+	if err := c.do(ctx, http.MethodGet, "/v1/movies", queryValues(filters), nil, &out); err != nil {
+		return nil, err
+	}
+	return &out, nil
+}
+
+func (c *Client) SearchMovies(ctx context.Context, search data.SearchMovie) ([]*data.SearchResult, *data.Metadata, error) {
+	var out struct {
+		Movies   []*data.SearchResult `json:"movies"`
+		Metadata *data.Metadata       `json:"metadata"`
+	}
+	if err := c.do(ctx, http.MethodGet, "/v1/movies/search", queryValues(search), nil, &out); err != nil {
+		return nil, nil, err
+	}
+	return out.Movies, out.Metadata, nil
+}
+
+// AutocompleteMovies suggests movies for the start of a title, limit defaults to
+// 10 when 0.
+func (c *Client) AutocompleteMovies(ctx context.Context, prefix string, limit int) ([]*data.Suggestion, error) {
+	q := url.Values{"q": {prefix}}
+	if limit > 0 {
+		q.Set("limit", strconv.Itoa(limit))
+	}
+	var out struct {
+		Suggestions []*data.Suggestion `json:"suggestions"`
+	}
+	if err := c.do(ctx, http.MethodGet, "/v1/movies/autocomplete", q, nil, &out); err != nil {
+		return nil, err
+	}
+	return out.Suggestions, nil
+}
+
+// MovieStats returns the statistics of the catalogue, with the movies created
+// per day over the last days when days isn't 0.
+func (c *Client) MovieStats(ctx context.Context, days int) (*data.Stats, error) {
+	var q url.Values
+	if days > 0 {
+		q = url.Values{"series": {"true"}, "days": {strconv.Itoa(days)}}
+	}
+	var out struct {
+		Stats *data.Stats `json:"stats"`
+	}
+	if err := c.do(ctx, http.MethodGet, "/v1/movies/stats", q, nil, &out); err != nil {
+		return nil, err
+	}
+	return out.Stats, nil
+}
+
+// ExportMovies writes every movie matching the filters to w, format is one of
+// ndjson, csv and json.
+func (c *Client) ExportMovies(ctx context.Context, w io.Writer, format string, filters data.ListMovie) error {
+	q := queryValues(filters)
+	q.Set("format", format)
+	return c.do(ctx, http.MethodGet, "/v1/movies/export", q, nil, w)
+}
+
+// ImportResult is the report of ImportMovies.
+type ImportResult struct {
+	DryRun  bool           `json:"dry_run"`
+	Summary map[string]int `json:"summary"`
+	Rows    []*ImportRow   `json:"rows"`
+}
+
+type ImportRow struct {
+	Row    int               `json:"row"`
+	Status string            `json:"status"`
+	ID     int64             `json:"id,omitempty"`
+	Errors map[string]string `json:"errors,omitempty"`
+}
+
+// ImportMovies uploads movies as text/csv or application/x-ndjson. With dryRun
+// nothing is written but the report tells what would have happened.
+func (c *Client) ImportMovies(ctx context.Context, contentType string, r io.Reader, dryRun bool) (*ImportResult, error) {
+	body, err := io.ReadAll(r)
+	if err != nil {
+		return nil, err
+	}
+	var q url.Values
+	if dryRun {
+		q = url.Values{"dry_run": {"true"}}
+	}
+	var out ImportResult
+	if err := c.do(ctx, http.MethodPost, "/v1/movies/import", q, rawBody{contentType, body}, &out); err != nil {
+		return nil, err
+	}
+	return &out, nil
+}
+
+// Modes of BatchMovies
+const (
+	BatchAtomic     = "atomic"
+	BatchBestEffort = "best_effort"
+)
+
+// BatchOp is one operation of BatchMovies. Movie is a *data.Movie for
+// data.BatchCreate and a *data.MovieUpdate for data.BatchUpdate, which also
+// needs the Version it was written against.
+type BatchOp struct {
+	Op      string `json:"op"`
+	ID      int64  `json:"id,omitempty"`
+	Version int32  `json:"version,omitempty"`
+	Movie   any    `json:"movie,omitempty"`
+}
+
+type BatchResult struct {
+	Index  int               `json:"index"`
+	Op     string            `json:"op"`
+	Status string            `json:"status"`
+	Movie  *data.Movie       `json:"movie,omitempty"`
+	Errors map[string]string `json:"errors,omitempty"`
+	Error  string            `json:"error,omitempty"`
+}
+
+// BatchMovies runs the operations in one request. When an atomic batch fails the
+// results are in the "results" extension of the returned *Error.
+func (c *Client) BatchMovies(ctx context.Context, mode string, ops []BatchOp) ([]*BatchResult, error) {
+	in := struct {
+		Mode       string    `json:"mode,omitempty"`
+		Operations []BatchOp `json:"operations"`
+	}{mode, ops}
+	var out struct {
+		Results []*BatchResult `json:"results"`
+	}
+	if err := c.do(ctx, http.MethodPost, "/v1/movies/batch", nil, in, &out); err != nil {
+		return nil, err
+	}
+	return out.Results, nil
+}
+
+// UpdateMovie changes the fields of update which are not nil.
+func (c *Client) UpdateMovie(ctx context.Context, id int64, update data.MovieUpdate) (*data.Movie, error) {
+	var out movieEnvelope
+	if err := c.do(ctx, http.MethodPatch, moviePath(id), nil, update, &out); err != nil {
+		return nil, err
+	}
+	return out.Movie, nil
+}
+
+// PatchMovie sends a JSON merge patch (a JSON object) or a JSON patch (an array
+// of operations), depending on contentType.
+func (c *Client) PatchMovie(ctx context.Context, id int64, contentType string, patch json.RawMessage) (*data.Movie, error) {
+	var out movieEnvelope
+	if err := c.do(ctx, http.MethodPatch, moviePath(id), nil, rawBody{contentType, patch}, &out); err != nil {
+		return nil, err
+	}
+	return out.Movie, nil
+}
+
+// ReplaceMovie replaces every field of the movie, movie.Version is required and
+// guards against concurrent updates.
+func (c *Client) ReplaceMovie(ctx context.Context, id int64, movie *data.Movie) (*data.Movie, error) {
+	var out movieEnvelope
+	if err := c.do(ctx, http.MethodPut, moviePath(id), nil, movie, &out); err != nil {
+		return nil, err
+	}
+	return out.Movie, nil
+}
+
+func (c *Client) DeleteMovie(ctx context.Context, id int64) error {
+	return c.do(ctx, http.MethodDelete, moviePath(id), nil, nil, nil)
+}
+
+// MergeMovie folds the movie sourceID into the movie id, which is kept. The kept
+// movie has the genres of both, unless genres are given.
+func (c *Client) MergeMovie(ctx context.Context, id, sourceID int64, genres ...string) (*data.Movie, error) {
+	in := map[string]any{"source_id": sourceID}
+	if len(genres) > 0 {
+		in["genres"] = genres
+	}
+	var out movieEnvelope
+	if err := c.do(ctx, http.MethodPost, moviePath(id)+"/merge", nil, in, &out); err != nil {
+		return nil, err
+	}
+	return out.Movie, nil
+}
+
+// DuplicateOptions tune ListDuplicateMovies, the server defaults apply to zero
+// values.
+type DuplicateOptions struct {
+	Threshold        float64 `form:"threshold"`
+	YearTolerance    int     `form:"year_tolerance"`
+	RuntimeTolerance int     `form:"runtime_tolerance"`
+	data.Filters
+}
+
+func (c *Client) ListDuplicateMovies(ctx context.Context, opts DuplicateOptions) ([]*data.DuplicateCandidate, *data.Metadata, error) {
+	var out struct {
+		Candidates []*data.DuplicateCandidate `json:"candidates"`
+		Metadata   *data.Metadata             `json:"metadata"`
+	}
+	if err := c.do(ctx, http.MethodGet, "/v1/movies/duplicates", queryValues(opts), nil, &out); err != nil {
+		return nil, nil, err
+	}
+	return out.Candidates, out.Metadata, nil
+}
+
+func externalPath(source, externalID string) string {
+	return "/v1/movies/by-external/" + url.PathEscape(source) + "/" + url.PathEscape(externalID)
+}
+
+// GetMovieByExternalID returns the movie known under an id of a partner catalogue.
+func (c *Client) GetMovieByExternalID(ctx context.Context, source, externalID string) (*data.Movie, error) {
+	var out movieEnvelope
+	if err := c.do(ctx, http.MethodGet, externalPath(source, externalID), nil, nil, &out); err != nil {
+		return nil, err
+	}
+	return out.Movie, nil
+}
+
+// UpsertMovieByExternalID creates or replaces the movie known under an id of a
+// partner catalogue. The status is data.ImportCreated or data.ImportUpdated.
+func (c *Client) UpsertMovieByExternalID(ctx context.Context, source, externalID string, movie *data.Movie) (*data.Movie, string, error) {
+	var out struct {
+		Movie  *data.Movie `json:"movie"`
+		Status string      `json:"status"`
+	}
+	if err := c.do(ctx, http.MethodPut, externalPath(source, externalID), nil, movie, &out); err != nil {
+		return nil, "", err
+	}
+	return out.Movie, out.Status, nil
+}
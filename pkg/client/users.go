@@ -0,0 +1,45 @@
+package client
+
+import (
+	"context"
+	"mdb/internal/data"
+	"net/http"
+)
+
+// RegisterUser creates a user, the activation token is sent to their email.
+func (c *Client) RegisterUser(ctx context.Context, name, email, password string) (*data.User, error) {
+	in := map[string]string{"name": name, "email": email, "password": password}
+	var out struct {
+		User *data.User `json:"user"`
+	}
+	if err := c.send(ctx, http.MethodPost, "/v1/users", nil, in, &out, false); err != nil {
+		return nil, err
+	}
+	return out.User, nil
+}
+
+// ActivateUser activates the user of an activation token.
+func (c *Client) ActivateUser(ctx context.Context, token string) (*data.User, error) {
+	in := map[string]string{"token": token, "scope": data.ScopeActivation}
+	var out struct {
+		User *data.User `json:"user"`
+	}
+	if err := c.send(ctx, http.MethodPut, "/v1/users/activated", nil, in, &out, false); err != nil {
+		return nil, err
+	}
+	return out.User, nil
+}
+
+// Health is the answer of the healthcheck.
+type Health struct {
+	Status     string            `json:"status"`
+	SystemInfo map[string]string `json:"system_info"`
+}
+
+func (c *Client) Healthcheck(ctx context.Context) (*Health, error) {
+	var out Health
+	if err := c.send(ctx, http.MethodGet, "/v1/healthcheck", nil, nil, &out, false); err != nil {
+		return nil, err
+	}
+	return &out, nil
+}